@@ -0,0 +1,56 @@
+// Commande import-challenges : importe les fichiers challenge.yml d'un dossier,
+// comme l'endpoint POST /api/admin/challenges/import.
+//
+//	go run ./cmd/import-challenges [-dir ../img_docker_chall] [-dry-run]
+package main
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Printf("No .env file loaded: %v", err)
+	}
+
+	dir := flag.String("dir", services.ChallengesDir(), "dossier contenant les challenge.yml")
+	dryRun := flag.Bool("dry-run", false, "afficher les différences sans modifier la base")
+	flag.Parse()
+
+	db.InitDB()
+	defer db.CloseDB()
+
+	if err := services.InitStorage(); err != nil {
+		log.Printf("Erreur lors de l'initialisation du stockage des pièces jointes: %v", err)
+	}
+
+	results, err := services.ImportChallengeDir(*dir, *dryRun)
+	if err != nil {
+		log.Fatalf("Erreur lors de la lecture de %s: %v", *dir, err)
+	}
+
+	failed := 0
+	for _, result := range results {
+		fmt.Printf("%-9s %-24s %s\n", result.Action, result.Slug, result.Path)
+		for _, change := range result.Changes {
+			fmt.Printf("          %s: %q -> %q\n", change.Field, change.From, change.To)
+		}
+		if result.Action == models.ImportFailed {
+			fmt.Printf("          error: %s\n", result.Error)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		db.CloseDB()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"log"
+)
+
+// CreateAnnouncement enregistre une annonce destinée aux joueurs
+func CreateAnnouncement(announcement *models.Announcement) error {
+	result, err := DB.Exec(`INSERT INTO announcements (type, message, challenge_id, user_id, team_id, event_id)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		announcement.Type, announcement.Message, announcement.ChallengeID, announcement.UserID, announcement.TeamID,
+		announcement.EventID)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	announcement.ID = int(id)
+	return nil
+}
+
+// GetAnnouncements récupère les annonces les plus récentes
+func GetAnnouncements(limit int) ([]models.Announcement, error) {
+	rows, err := DB.Query(`SELECT id, type, message, challenge_id, user_id, team_id, event_id, created_at
+		FROM announcements
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	announcements := []models.Announcement{}
+	for rows.Next() {
+		var announcement models.Announcement
+		var challengeID, userID, teamID, eventID sql.NullInt64
+		var createdAtStr string
+
+		err := rows.Scan(&announcement.ID, &announcement.Type, &announcement.Message,
+			&challengeID, &userID, &teamID, &eventID, &createdAtStr)
+		if err != nil {
+			log.Printf("Error scanning announcement: %v", err)
+			continue
+		}
+
+		announcement.ChallengeID = nullableInt(challengeID)
+		announcement.UserID = nullableInt(userID)
+		announcement.TeamID = nullableInt(teamID)
+		announcement.EventID = nullableInt(eventID)
+		if announcement.CreatedAt, err = parseTimestamp(createdAtStr); err != nil {
+			log.Printf("Warning: failed to parse created_at date: %v", err)
+		}
+		announcements = append(announcements, announcement)
+	}
+
+	return announcements, rows.Err()
+}
+
+// nullableInt convertit un entier SQL nullable en pointeur
+func nullableInt(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	id := int(value.Int64)
+	return &id
+}
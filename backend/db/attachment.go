@@ -0,0 +1,88 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// CreateAttachment enregistre une pièce jointe déjà écrite dans le stockage
+func CreateAttachment(attachment *models.Attachment) error {
+	return createAttachment(DB, attachment)
+}
+
+func createAttachment(conn execer, attachment *models.Attachment) error {
+	result, err := conn.Exec(`INSERT INTO attachments (challenge_id, filename, storage_key, size, sha256)
+		VALUES (?, ?, ?, ?, ?)`,
+		attachment.ChallengeID, attachment.Filename, attachment.StorageKey, attachment.Size, attachment.SHA256)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	attachment.ID = int(id)
+	return nil
+}
+
+// GetAttachmentByID récupère une pièce jointe par son ID
+func GetAttachmentByID(id int) (*models.Attachment, error) {
+	var attachment models.Attachment
+	var createdAtStr string
+
+	err := DB.QueryRow(`SELECT id, challenge_id, filename, storage_key, size, sha256, created_at
+		FROM attachments WHERE id = ?`, id).
+		Scan(&attachment.ID, &attachment.ChallengeID, &attachment.Filename, &attachment.StorageKey,
+			&attachment.Size, &attachment.SHA256, &createdAtStr)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if attachment.CreatedAt, err = parseTimestamp(createdAtStr); err != nil {
+		log.Printf("Warning: failed to parse created_at date: %v", err)
+	}
+	return &attachment, nil
+}
+
+// GetChallengeAttachments récupère les pièces jointes d'un challenge
+func GetChallengeAttachments(challengeID int) ([]models.Attachment, error) {
+	rows, err := DB.Query(`SELECT id, challenge_id, filename, storage_key, size, sha256, created_at
+		FROM attachments WHERE challenge_id = ? ORDER BY id ASC`, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		var attachment models.Attachment
+		var createdAtStr string
+
+		err := rows.Scan(&attachment.ID, &attachment.ChallengeID, &attachment.Filename, &attachment.StorageKey,
+			&attachment.Size, &attachment.SHA256, &createdAtStr)
+		if err != nil {
+			log.Printf("Error scanning attachment: %v", err)
+			continue
+		}
+
+		if attachment.CreatedAt, err = parseTimestamp(createdAtStr); err != nil {
+			log.Printf("Warning: failed to parse created_at date: %v", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+// DeleteAttachment supprime l'enregistrement d'une pièce jointe
+func DeleteAttachment(id int) error {
+	_, err := DB.Exec("DELETE FROM attachments WHERE id = ?", id)
+	return err
+}
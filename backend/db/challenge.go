@@ -10,17 +10,37 @@ import (
 
 
 
-// GetChallengeByID récupère un challenge par son ID
+// GetChallengeByID récupère un challenge actif par son ID
 func GetChallengeByID(id int) (*models.Challenge, error) {
-	row := DB.QueryRow(`SELECT id, name, description, docker_image, port, cpu_limit, memory_limit, created_at 
-		FROM challenges WHERE id = ? AND is_active = 1`, id)
+	return getChallenge(id, true)
+}
+
+// GetChallengeIncludingInactive récupère un challenge par son ID, même désactivé (administration)
+func GetChallengeIncludingInactive(id int) (*models.Challenge, error) {
+	return getChallenge(id, false)
+}
+
+func getChallenge(id int, activeOnly bool) (*models.Challenge, error) {
+	query := `SELECT id, name, description, category, difficulty, points, flag, docker_image, port, cpu_limit, memory_limit, time_limit, created_at,
+		IFNULL(scoring_type, 'static'), IFNULL(initial_value, 0), IFNULL(minimum_value, 0), IFNULL(decay, 0), is_active,
+		IFNULL(slug, ''), event_id, visible_from, visible_until
+		FROM challenges WHERE id = ?`
+	if activeOnly {
+		query += " AND is_active = 1"
+	}
+	row := DB.QueryRow(query, id)
 	
 	var challenge models.Challenge
 	var createdAtStr string
+	var eventID sql.NullInt64
+	var visibleFrom, visibleUntil sql.NullTime
 	
 	err := row.Scan(&challenge.ID, &challenge.Name, &challenge.Description, 
+		&challenge.Category, &challenge.Difficulty, &challenge.Points, &challenge.Flag,
 		&challenge.DockerImage, &challenge.Port, &challenge.CPULimit, 
-		&challenge.MemoryLimit, &createdAtStr)
+		&challenge.MemoryLimit, &challenge.TimeLimit, &createdAtStr,
+		&challenge.ScoringType, &challenge.InitialValue, &challenge.MinimumValue, &challenge.Decay,
+		&challenge.IsActive, &challenge.Slug, &eventID, &visibleFrom, &visibleUntil)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -29,29 +49,66 @@ func GetChallengeByID(id int) (*models.Challenge, error) {
 	}
 	
 	challenge.CreatedAt = createdAtStr
+	if eventID.Valid {
+		id := int(eventID.Int64)
+		challenge.EventID = &id
+	}
+	if visibleFrom.Valid {
+		challenge.VisibleFrom = &visibleFrom.Time
+	}
+	if visibleUntil.Valid {
+		challenge.VisibleUntil = &visibleUntil.Time
+	}
 	return &challenge, nil
 }
 
+// GetChallengeBySlug récupère un challenge importé par son slug, même désactivé
+func GetChallengeBySlug(slug string) (*models.Challenge, error) {
+	return findChallenge("slug = ?", slug)
+}
+
+// GetUnsluggedChallengeByName récupère un challenge sans slug par son nom, pour
+// rattacher à leur challenge.yml les challenges créés avant l'import
+func GetUnsluggedChallengeByName(name string) (*models.Challenge, error) {
+	return findChallenge("slug IS NULL AND name = ?", name)
+}
+
+func findChallenge(filter string, arg interface{}) (*models.Challenge, error) {
+	var id int
+	err := DB.QueryRow("SELECT id FROM challenges WHERE "+filter+" ORDER BY id LIMIT 1", arg).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return getChallenge(id, false)
+}
+
 // CreateChallenge crée un nouveau challenge
 func CreateChallenge(challenge *models.Challenge) (int, error) {
-	stmt, err := DB.Prepare(`INSERT INTO challenges (name, description, category, difficulty, points, flag, docker_image, port, cpu_limit, memory_limit, time_limit) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	return createChallenge(DB, challenge)
+}
+
+func createChallenge(conn execer, challenge *models.Challenge) (int, error) {
+	stmt, err := conn.Prepare(`INSERT INTO challenges (name, description, category, difficulty, points, flag, docker_image, port,
+		cpu_limit, memory_limit, time_limit, is_active, created_by, slug, event_id, visible_from, visible_until, release_announced)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
 
-	// Valeurs par défaut si non définies
-	category := "general"
-	difficulty := "medium"
-	points := 100
-	flag := "CTF{placeholder}"
-	dockerImage := "nginx:latest"
-	cpuLimit := "0.5"
-	memoryLimit := "512Mi"
-	timeLimit := 3600
-
-	result, err := stmt.Exec(challenge.Name, challenge.Description, category, difficulty, points, flag, dockerImage, challenge.Port, cpuLimit, memoryLimit, timeLimit)
+	var createdBy interface{}
+	if challenge.CreatedBy != 0 {
+		createdBy = challenge.CreatedBy
+	}
+
+	result, err := stmt.Exec(challenge.Name, challenge.Description, challenge.Category, challenge.Difficulty,
+		challenge.Points, challenge.Flag, challenge.DockerImage, challenge.Port, challenge.CPULimit,
+		challenge.MemoryLimit, challenge.TimeLimit, challenge.IsActive, createdBy, nullableString(challenge.Slug),
+		nullableID(challenge.EventID), utcOrNil(challenge.VisibleFrom), utcOrNil(challenge.VisibleUntil),
+		!releasePending(challenge.VisibleFrom, time.Now()))
 	if err != nil {
 		return 0, err
 	}
@@ -64,6 +121,48 @@ func CreateChallenge(challenge *models.Challenge) (int, error) {
 	return int(id), nil
 }
 
+// UpdateChallenge enregistre toutes les colonnes modifiables d'un challenge. Si sa
+// valeur change (points d'un challenge statique, valeur initiale d'un challenge
+// dynamique), les scores de ses solveurs sont mis à jour dans la même transaction.
+func UpdateChallenge(challenge *models.Challenge) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	value, err := updateChallenge(tx, challenge)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	challenge.Points = value
+	return nil
+}
+
+// updateChallenge enregistre le challenge et retourne sa valeur recalculée
+func updateChallenge(tx *sql.Tx, challenge *models.Challenge) (int, error) {
+	initialValue := challenge.InitialValue
+	if challenge.ScoringType != models.ScoringDynamic {
+		initialValue = challenge.Points
+	}
+
+	_, err := tx.Exec(`UPDATE challenges SET name = ?, description = ?, category = ?, difficulty = ?, flag = ?,
+		docker_image = ?, port = ?, cpu_limit = ?, memory_limit = ?, time_limit = ?, is_active = ?, initial_value = ?,
+		slug = ?, event_id = ? WHERE id = ?`,
+		challenge.Name, challenge.Description, challenge.Category, challenge.Difficulty, challenge.Flag,
+		challenge.DockerImage, challenge.Port, challenge.CPULimit, challenge.MemoryLimit, challenge.TimeLimit,
+		challenge.IsActive, initialValue, nullableString(challenge.Slug), nullableID(challenge.EventID), challenge.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return recomputeChallengeValue(tx, challenge.ID)
+}
+
 // DeleteChallenge supprime un challenge (soft delete)
 func DeleteChallenge(id int) error {
 	_, err := DB.Exec("UPDATE challenges SET is_active = 0 WHERE id = ?", id)
@@ -104,14 +203,14 @@ func GetUserInstances(userID int) ([]models.Instance, error) {
 		}
 
 		// Parse les dates
-		if instance.StartedAt, err = time.Parse("2006-01-02 15:04:05", startedAtStr); err != nil {
+		if instance.StartedAt, err = parseTimestamp(startedAtStr); err != nil {
 			log.Printf("Warning: failed to parse started_at date: %v", err)
 		}
-		if instance.ExpiresAt, err = time.Parse("2006-01-02 15:04:05", expiresAtStr); err != nil {
+		if instance.ExpiresAt, err = parseTimestamp(expiresAtStr); err != nil {
 			log.Printf("Warning: failed to parse expires_at date: %v", err)
 		}
 		if lastAccessed.Valid {
-			if instance.LastAccessed, err = time.Parse("2006-01-02 15:04:05", lastAccessed.String); err != nil {
+			if instance.LastAccessed, err = parseTimestamp(lastAccessed.String); err != nil {
 				log.Printf("Warning: failed to parse last_accessed date: %v", err)
 			}
 		}
@@ -143,10 +242,20 @@ func GetUserInstances(userID int) ([]models.Instance, error) {
 
 // GetUserChallengeInstances récupère les instances d'un utilisateur pour un challenge spécifique
 func GetUserChallengeInstances(userID, challengeID int) ([]models.Instance, error) {
+	return getActiveChallengeInstances("user_id = ?", userID, challengeID)
+}
+
+// GetTeamChallengeInstances récupère les instances des membres d'une équipe pour un challenge spécifique
+func GetTeamChallengeInstances(teamID, challengeID int) ([]models.Instance, error) {
+	return getActiveChallengeInstances("user_id IN (SELECT id FROM users WHERE team_id = ?)", teamID, challengeID)
+}
+
+// getActiveChallengeInstances récupère les instances actives d'un challenge filtrées par propriétaire
+func getActiveChallengeInstances(ownerFilter string, ownerID, challengeID int) ([]models.Instance, error) {
 	rows, err := DB.Query(`SELECT id, user_id, challenge_id, container_name, pod_name, service_name, 
 		namespace, external_port, internal_port, access_url, status, started_at, expires_at, last_accessed
 		FROM instances 
-		WHERE user_id = ? AND challenge_id = ? AND status IN ('creating', 'running', 'ready')`, userID, challengeID)
+		WHERE `+ownerFilter+` AND challenge_id = ? AND status IN ('creating', 'running', 'ready', 'failed', 'crashloop')`, ownerID, challengeID)
 	if err != nil {
 		return nil, err
 	}
@@ -169,14 +278,14 @@ func GetUserChallengeInstances(userID, challengeID int) ([]models.Instance, erro
 		}
 
 		// Parse les dates
-		if instance.StartedAt, err = time.Parse("2006-01-02 15:04:05", startedAtStr); err != nil {
+		if instance.StartedAt, err = parseTimestamp(startedAtStr); err != nil {
 			log.Printf("Warning: failed to parse started_at date: %v", err)
 		}
-		if instance.ExpiresAt, err = time.Parse("2006-01-02 15:04:05", expiresAtStr); err != nil {
+		if instance.ExpiresAt, err = parseTimestamp(expiresAtStr); err != nil {
 			log.Printf("Warning: failed to parse expires_at date: %v", err)
 		}
 		if lastAccessed.Valid {
-			if instance.LastAccessed, err = time.Parse("2006-01-02 15:04:05", lastAccessed.String); err != nil {
+			if instance.LastAccessed, err = parseTimestamp(lastAccessed.String); err != nil {
 				log.Printf("Warning: failed to parse last_accessed date: %v", err)
 			}
 		}
@@ -206,12 +315,15 @@ func GetUserChallengeInstances(userID, challengeID int) ([]models.Instance, erro
 
 // CreateInstance crée une nouvelle instance
 func CreateInstance(instance *models.Instance) (int, error) {
-	// Définir une date d'expiration (par exemple, 2 heures après la création)
-	expiresAt := time.Now().Add(2 * time.Hour)
+	// Sans date d'expiration fournie, l'instance vit le time_limit par défaut (1 heure)
+	if instance.ExpiresAt.IsZero() {
+		instance.ExpiresAt = time.Now().Add(time.Hour)
+	}
+	expiresAt := instance.ExpiresAt.UTC()
 
 	stmt, err := DB.Prepare(`INSERT INTO instances (user_id, challenge_id, container_name, pod_name, service_name, 
-		namespace, external_port, internal_port, access_url, status, expires_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		namespace, external_port, internal_port, access_url, status, expires_at, flag) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return 0, err
 	}
@@ -219,7 +331,7 @@ func CreateInstance(instance *models.Instance) (int, error) {
 
 	result, err := stmt.Exec(instance.UserID, instance.ChallengeID, instance.ContainerName,
 		instance.PodName, instance.ServiceName, instance.Namespace, instance.ExternalPort,
-		instance.InternalPort, instance.AccessURL, instance.Status, expiresAt)
+		instance.InternalPort, instance.AccessURL, instance.Status, expiresAt, instance.Flag)
 	if err != nil {
 		return 0, err
 	}
@@ -232,21 +344,31 @@ func CreateInstance(instance *models.Instance) (int, error) {
 	return int(id), nil
 }
 
+// UpdateInstanceResources enregistre les ressources allouées à une instance par son backend
+func UpdateInstanceResources(instance *models.Instance) error {
+	_, err := DB.Exec(`UPDATE instances SET container_name = ?, pod_name = ?, service_name = ?, namespace = ?,
+		external_port = ?, internal_port = ?, access_url = ?, flag = ?
+		WHERE id = ?`,
+		instance.ContainerName, instance.PodName, instance.ServiceName, instance.Namespace,
+		instance.ExternalPort, instance.InternalPort, instance.AccessURL, instance.Flag, instance.ID)
+	return err
+}
+
 // GetInstanceByID récupère une instance par son ID
 func GetInstanceByID(id int) (*models.Instance, error) {
 	row := DB.QueryRow(`SELECT id, user_id, challenge_id, container_name, pod_name, service_name, 
-		namespace, external_port, internal_port, access_url, status, started_at, expires_at, last_accessed
+		namespace, external_port, internal_port, access_url, status, status_reason, started_at, expires_at, last_accessed
 		FROM instances WHERE id = ?`, id)
 
 	var instance models.Instance
 	var startedAtStr, expiresAtStr string
-	var podName, serviceName, accessURL sql.NullString
+	var podName, serviceName, accessURL, statusReason sql.NullString
 	var externalPort, internalPort sql.NullInt64
 	var lastAccessed sql.NullString
 
 	err := row.Scan(&instance.ID, &instance.UserID, &instance.ChallengeID, &instance.ContainerName,
 		&podName, &serviceName, &instance.Namespace, &externalPort, &internalPort, &accessURL,
-		&instance.Status, &startedAtStr, &expiresAtStr, &lastAccessed)
+		&instance.Status, &statusReason, &startedAtStr, &expiresAtStr, &lastAccessed)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -255,14 +377,14 @@ func GetInstanceByID(id int) (*models.Instance, error) {
 	}
 
 	// Parse les dates
-	if instance.StartedAt, err = time.Parse("2006-01-02 15:04:05", startedAtStr); err != nil {
+	if instance.StartedAt, err = parseTimestamp(startedAtStr); err != nil {
 		log.Printf("Warning: failed to parse started_at date: %v", err)
 	}
-	if instance.ExpiresAt, err = time.Parse("2006-01-02 15:04:05", expiresAtStr); err != nil {
+	if instance.ExpiresAt, err = parseTimestamp(expiresAtStr); err != nil {
 		log.Printf("Warning: failed to parse expires_at date: %v", err)
 	}
 	if lastAccessed.Valid {
-		if instance.LastAccessed, err = time.Parse("2006-01-02 15:04:05", lastAccessed.String); err != nil {
+		if instance.LastAccessed, err = parseTimestamp(lastAccessed.String); err != nil {
 			log.Printf("Warning: failed to parse last_accessed date: %v", err)
 		}
 	}
@@ -283,6 +405,7 @@ func GetInstanceByID(id int) (*models.Instance, error) {
 	if internalPort.Valid {
 		instance.InternalPort = int(internalPort.Int64)
 	}
+	instance.StatusReason = statusReason.String
 
 	return &instance, nil
 }
@@ -291,4 +414,113 @@ func GetInstanceByID(id int) (*models.Instance, error) {
 func DeleteInstance(id int) error {
 	_, err := DB.Exec("UPDATE instances SET status = 'deleted' WHERE id = ?", id)
 	return err
-}
\ No newline at end of file
+}
+// GetExpiredInstances récupère les instances encore actives dont la date d'expiration est dépassée
+func GetExpiredInstances(now time.Time) ([]models.Instance, error) {
+	rows, err := DB.Query(`SELECT id, user_id, challenge_id, pod_name, service_name, namespace
+		FROM instances
+		WHERE expires_at IS NOT NULL AND expires_at <= ? AND status NOT IN ('expired', 'deleted', 'lost')`, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var instances []models.Instance
+	for rows.Next() {
+		var instance models.Instance
+		var podName, serviceName sql.NullString
+
+		err := rows.Scan(&instance.ID, &instance.UserID, &instance.ChallengeID,
+			&podName, &serviceName, &instance.Namespace)
+		if err != nil {
+			log.Printf("Error scanning expired instance: %v", err)
+			continue
+		}
+
+		instance.PodName = podName.String
+		instance.ServiceName = serviceName.String
+		instances = append(instances, instance)
+	}
+
+	return instances, rows.Err()
+}
+
+// UpdateInstanceStatus met à jour le statut d'une instance
+func UpdateInstanceStatus(id int, status string) error {
+	_, err := DB.Exec("UPDATE instances SET status = ? WHERE id = ?", status, id)
+	return err
+}
+
+// UpdateInstanceExpiry met à jour la date d'expiration d'une instance
+func UpdateInstanceExpiry(id int, expiresAt time.Time) error {
+	_, err := DB.Exec("UPDATE instances SET expires_at = ? WHERE id = ?", expiresAt.UTC(), id)
+	return err
+}
+
+// UpdateInstanceStatusByPodName met à jour le statut d'une instance active à partir du nom
+// de son Deployment. Retourne true si le statut a effectivement changé.
+func UpdateInstanceStatusByPodName(podName, status, reason string) (bool, error) {
+	result, err := DB.Exec(`UPDATE instances SET status = ?, status_reason = ?
+		WHERE pod_name = ? AND status NOT IN ('expired', 'deleted', 'lost')
+		AND (status != ? OR IFNULL(status_reason, '') != ?)`,
+		status, reason, podName, status, reason)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// GetActiveInstances récupère les instances qui devraient encore avoir des ressources actives
+func GetActiveInstances() ([]models.Instance, error) {
+	rows, err := DB.Query(`SELECT id, user_id, challenge_id, pod_name, service_name, namespace, status, started_at
+		FROM instances
+		WHERE status NOT IN ('expired', 'deleted', 'lost')`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var instances []models.Instance
+	for rows.Next() {
+		var instance models.Instance
+		var podName, serviceName sql.NullString
+		var startedAtStr string
+
+		err := rows.Scan(&instance.ID, &instance.UserID, &instance.ChallengeID,
+			&podName, &serviceName, &instance.Namespace, &instance.Status, &startedAtStr)
+		if err != nil {
+			log.Printf("Error scanning active instance: %v", err)
+			continue
+		}
+
+		if instance.StartedAt, err = parseTimestamp(startedAtStr); err != nil {
+			log.Printf("Warning: failed to parse started_at date: %v", err)
+		}
+		instance.PodName = podName.String
+		instance.ServiceName = serviceName.String
+		instances = append(instances, instance)
+	}
+
+	return instances, rows.Err()
+}
+
+// nullableString enregistre une chaîne vide comme NULL
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// nullableID enregistre un identifiant absent comme NULL
+func nullableID(id *int) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"os"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -12,10 +13,16 @@ import (
 
 var DB *sql.DB
 
-// InitDB ouvre la connexion et crée les tables si elles n'existent pas
+// InitDB ouvre la connexion et crée les tables si elles n'existent pas.
+// Le chemin de la base peut être surchargé avec DB_PATH.
 func InitDB() {
+	path := os.Getenv("DB_PATH")
+	if path == "" {
+		path = "./ctf.sqlite"
+	}
+
 	var err error
-	DB, err = sql.Open("sqlite3", "./ctf.sqlite")
+	DB, err = sql.Open("sqlite3", path)
 	if err != nil {
 		log.Fatal("Failed to open DB:", err)
 	}
@@ -51,7 +58,24 @@ func createTables() {
 			role TEXT DEFAULT 'user',
 			score INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			last_login DATETIME
+			last_login DATETIME,
+			team_id INTEGER,
+			FOREIGN KEY (team_id) REFERENCES teams(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS teams (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			invite_code TEXT UNIQUE NOT NULL,
+			captain_id INTEGER,
+			score INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (captain_id) REFERENCES users(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
 		);`,
 
 		`CREATE TABLE IF NOT EXISTS challenges (
@@ -67,8 +91,22 @@ func createTables() {
 			cpu_limit TEXT DEFAULT '0.5',
 			memory_limit TEXT DEFAULT '512Mi',
 			time_limit INTEGER DEFAULT 3600,
+			is_active BOOLEAN DEFAULT 1,
+			scoring_type TEXT DEFAULT 'static',
+			initial_value INTEGER,
+			minimum_value INTEGER,
+			decay INTEGER,
+			slug TEXT,
+			event_id INTEGER,
+			unlock_mode TEXT DEFAULT 'all',
+			unlock_score INTEGER DEFAULT 0,
+			visible_from DATETIME,
+			visible_until DATETIME,
+			release_announced BOOLEAN DEFAULT 0,
+			created_by INTEGER,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (created_by) REFERENCES users(id)
+			FOREIGN KEY (created_by) REFERENCES users(id),
+			FOREIGN KEY (event_id) REFERENCES events(id)
 		);`,
 
 		`CREATE TABLE IF NOT EXISTS instances (
@@ -86,6 +124,8 @@ func createTables() {
 			started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			expires_at DATETIME,
 			last_accessed DATETIME,
+			flag TEXT,
+			status_reason TEXT,
 			FOREIGN KEY (user_id) REFERENCES users(id),
 			FOREIGN KEY (challenge_id) REFERENCES challenges(id)
 		);`,
@@ -95,9 +135,11 @@ func createTables() {
 			user_id INTEGER NOT NULL,
 			challenge_id INTEGER NOT NULL,
 			instance_id INTEGER,
+			team_id INTEGER,
 			submitted_flag TEXT NOT NULL,
 			is_valid BOOLEAN NOT NULL,
 			points_awarded INTEGER DEFAULT 0,
+			flag_id INTEGER,
 			submitted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id),
 			FOREIGN KEY (challenge_id) REFERENCES challenges(id),
@@ -108,12 +150,152 @@ func createTables() {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
 			challenge_id INTEGER NOT NULL,
+			team_id INTEGER,
+			solve_rank INTEGER,
+			bonus INTEGER DEFAULT 0,
 			solved_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			UNIQUE(user_id, challenge_id),
+			FOREIGN KEY (team_id) REFERENCES teams(id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (challenge_id) REFERENCES challenges(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS flag_reviews (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			submission_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			challenge_id INTEGER NOT NULL,
+			instance_id INTEGER NOT NULL,
+			owner_id INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (submission_id) REFERENCES submissions(id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (challenge_id) REFERENCES challenges(id),
+			FOREIGN KEY (instance_id) REFERENCES instances(id),
+			FOREIGN KEY (owner_id) REFERENCES users(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS hints (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			challenge_id INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			cost INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (challenge_id) REFERENCES challenges(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS hint_unlocks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			hint_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			team_id INTEGER,
+			cost INTEGER NOT NULL,
+			unlocked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(hint_id, user_id),
+			FOREIGN KEY (hint_id) REFERENCES hints(id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (team_id) REFERENCES teams(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS flags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			challenge_id INTEGER NOT NULL,
+			type TEXT NOT NULL DEFAULT 'static',
+			content TEXT NOT NULL,
+			case_sensitive BOOLEAN DEFAULT 1,
+			points INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (challenge_id) REFERENCES challenges(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS flag_captures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			flag_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			team_id INTEGER,
+			points INTEGER NOT NULL,
+			captured_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(flag_id, user_id),
+			FOREIGN KEY (flag_id) REFERENCES flags(id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (team_id) REFERENCES teams(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT,
+			name TEXT NOT NULL,
+			namespace TEXT,
+			start_time DATETIME NOT NULL,
+			end_time DATETIME NOT NULL,
+			freeze_time DATETIME,
+			paused_at DATETIME,
+			mode TEXT,
+			share_instances BOOLEAN,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS event_registrations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			registered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(event_id, user_id),
+			FOREIGN KEY (event_id) REFERENCES events(id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS challenge_prerequisites (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			challenge_id INTEGER NOT NULL,
+			prerequisite_id INTEGER NOT NULL,
+			UNIQUE(challenge_id, prerequisite_id),
+			FOREIGN KEY (challenge_id) REFERENCES challenges(id),
+			FOREIGN KEY (prerequisite_id) REFERENCES challenges(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS suspicious_activities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			type TEXT NOT NULL,
+			user_id INTEGER,
+			challenge_id INTEGER,
+			ip TEXT,
+			details TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id),
 			FOREIGN KEY (challenge_id) REFERENCES challenges(id)
 		);`,
 
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			challenge_id INTEGER NOT NULL,
+			filename TEXT NOT NULL,
+			storage_key TEXT UNIQUE NOT NULL,
+			size INTEGER NOT NULL,
+			sha256 TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (challenge_id) REFERENCES challenges(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS announcements (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			type TEXT NOT NULL,
+			message TEXT NOT NULL,
+			challenge_id INTEGER,
+			user_id INTEGER,
+			team_id INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (challenge_id) REFERENCES challenges(id),
+			FOREIGN KEY (user_id) REFERENCES users(id),
+			FOREIGN KEY (team_id) REFERENCES teams(id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS port_allocations (
+			port INTEGER PRIMARY KEY,
+			pod_name TEXT NOT NULL,
+			allocated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS user_sessions (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
@@ -131,11 +313,123 @@ func createTables() {
 		}
 	}
 
+	migrateTables()
+
 	// Créer un utilisateur admin par défaut
 	createDefaultAdmin()
 	insertDefaultChallenges()
 }
 
+// migrateTables ajoute aux bases existantes les colonnes apparues après
+// la création initiale du schéma
+func migrateTables() {
+	columns := []struct {
+		Table      string
+		Column     string
+		Definition string
+	}{
+		{"instances", "flag", "TEXT"},
+		{"instances", "status_reason", "TEXT"},
+		{"users", "team_id", "INTEGER REFERENCES teams(id)"},
+		{"submissions", "team_id", "INTEGER"},
+		{"challenge_solves", "team_id", "INTEGER REFERENCES teams(id)"},
+		{"challenges", "scoring_type", "TEXT DEFAULT 'static'"},
+		{"challenges", "initial_value", "INTEGER"},
+		{"challenges", "minimum_value", "INTEGER"},
+		{"challenges", "decay", "INTEGER"},
+		{"challenge_solves", "solve_rank", "INTEGER"},
+		{"challenge_solves", "bonus", "INTEGER DEFAULT 0"},
+		{"challenges", "slug", "TEXT"},
+		{"submissions", "flag_id", "INTEGER"},
+		{"events", "slug", "TEXT"},
+		{"events", "namespace", "TEXT"},
+		{"challenges", "event_id", "INTEGER REFERENCES events(id)"},
+		{"challenges", "unlock_mode", "TEXT DEFAULT 'all'"},
+		{"challenges", "unlock_score", "INTEGER DEFAULT 0"},
+		{"challenges", "visible_from", "DATETIME"},
+		{"challenges", "visible_until", "DATETIME"},
+		{"challenges", "release_announced", "BOOLEAN DEFAULT 0"},
+		{"announcements", "event_id", "INTEGER REFERENCES events(id)"},
+		{"events", "mode", "TEXT"},
+		{"events", "share_instances", "BOOLEAN"},
+	}
+
+	for _, col := range columns {
+		if err := addColumnIfMissing(col.Table, col.Column, col.Definition); err != nil {
+			log.Printf("Error migrating column %s.%s: %v", col.Table, col.Column, err)
+		}
+	}
+
+	// Rang des résolutions antérieures à l'ajout de la colonne solve_rank
+	_, err := DB.Exec(`UPDATE challenge_solves SET solve_rank = (
+		SELECT COUNT(*) FROM challenge_solves p
+		WHERE p.challenge_id = challenge_solves.challenge_id
+		AND (p.solved_at < challenge_solves.solved_at OR (p.solved_at = challenge_solves.solved_at AND p.id <= challenge_solves.id))
+	) WHERE solve_rank IS NULL`)
+	if err != nil {
+		log.Printf("Error backfilling solve ranks: %v", err)
+	}
+
+	// Une équipe ne résout un challenge qu'une seule fois
+	_, err = DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_challenge_solves_team
+		ON challenge_solves(team_id, challenge_id) WHERE team_id IS NOT NULL`)
+	if err != nil {
+		log.Printf("Error creating team solves index: %v", err)
+	}
+
+	// Une équipe ne valide un flag partiel qu'une seule fois
+	_, err = DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_flag_captures_team
+		ON flag_captures(flag_id, team_id) WHERE team_id IS NOT NULL`)
+	if err != nil {
+		log.Printf("Error creating team flag captures index: %v", err)
+	}
+
+	// Identifiant stable des challenges importés depuis un challenge.yml
+	_, err = DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_challenges_slug
+		ON challenges(slug) WHERE slug IS NOT NULL`)
+	if err != nil {
+		log.Printf("Error creating challenge slug index: %v", err)
+	}
+
+	// Les événements hébergés sont désignés par leur slug dans les routes
+	_, err = DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_events_slug
+		ON events(slug) WHERE slug IS NOT NULL`)
+	if err != nil {
+		log.Printf("Error creating event slug index: %v", err)
+	}
+}
+
+// addColumnIfMissing ajoute une colonne à une table si elle n'existe pas déjà
+func addColumnIfMissing(table, column, definition string) error {
+	rows, err := DB.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = DB.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	if err == nil {
+		log.Printf("Added column %s.%s", table, column)
+	}
+	return err
+}
+
 func insertDefaultChallenges() {
 	var count int
 	err := DB.QueryRow("SELECT COUNT(*) FROM challenges").Scan(&count)
@@ -163,7 +457,7 @@ func insertDefaultChallenges() {
 			Name:        "XSS Reflective",
 			Description: "Trouvez une faille XSS réfléchie dans ce mini site web.",
 			Category:    "Web",
-			Difficulty:  "Easy",
+			Difficulty:  "easy",
 			Points:      75,
 			Flag:        "CTF{xss_reflected_pwned}",
 			Image:       "xss-vuln", // ou "utilisateur/xss-vuln:latest" si hébergée sur Docker Hub
@@ -173,7 +467,7 @@ func insertDefaultChallenges() {
 			Name:        "CSRF Reflective",
 			Description: "Trouvez une faille CSRF réfléchie dans ce mini site web.",
 			Category:    "Web",
-			Difficulty:  "Easy",
+			Difficulty:  "easy",
 			Points:      75,
 			Flag:        "CTF{csrf_reflected_pwned}",
 			Image:       "xss-vuln", // ou "utilisateur/xss-vuln:latest" si hébergée sur Docker Hub
@@ -217,6 +511,22 @@ func createDefaultAdmin() {
 	}
 }
 
+// parseTimestamp analyse une date lue depuis SQLite, au format RFC3339 renvoyé
+// par le driver ou au format CURRENT_TIMESTAMP
+func parseTimestamp(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02 15:04:05", value)
+}
+
+// formatTimestamp formate une date comme CURRENT_TIMESTAMP, pour la comparer aux
+// colonnes remplies par défaut
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
 // GetUserByUsername récupère un utilisateur par son username
 func GetUserByUsername(username string) (*models.User, error) {
 	row := DB.QueryRow(`SELECT id, username, email, password_hash, role, score, created_at, last_login 
@@ -235,13 +545,13 @@ func GetUserByUsername(username string) (*models.User, error) {
 	}
 
 	// Parse dates
-	user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	user.CreatedAt, err = parseTimestamp(createdAtStr)
 	if err != nil {
 		log.Printf("Warning: failed to parse created_at date: %v", err)
 	}
 
 	if lastLoginStr.Valid {
-		user.LastLogin, err = time.Parse("2006-01-02 15:04:05", lastLoginStr.String)
+		user.LastLogin, err = parseTimestamp(lastLoginStr.String)
 		if err != nil {
 			log.Printf("Warning: failed to parse last_login date: %v", err)
 		}
@@ -297,13 +607,13 @@ func GetUserByID(id int) (*models.User, error) {
 	}
 
 	// Parse dates
-	user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	user.CreatedAt, err = parseTimestamp(createdAtStr)
 	if err != nil {
 		log.Printf("Warning: failed to parse created_at date: %v", err)
 	}
 
 	if lastLoginStr.Valid {
-		user.LastLogin, err = time.Parse("2006-01-02 15:04:05", lastLoginStr.String)
+		user.LastLogin, err = parseTimestamp(lastLoginStr.String)
 		if err != nil {
 			log.Printf("Warning: failed to parse last_login date: %v", err)
 		}
@@ -312,10 +622,12 @@ func GetUserByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
-// GetAllChallenges récupère tous les challenges actifs
-func GetAllChallenges() ([]models.Challenge, error) {
+// GetAllChallenges récupère tous les challenges actifs et publiés d'un événement
+// hébergé, ou ceux de la plateforme (sans événement) si eventID vaut 0
+func GetAllChallenges(eventID int) ([]models.Challenge, error) {
+	visible, args := VisibleChallengeFilter(time.Now())
 	rows, err := DB.Query(`SELECT id, name, description, docker_image, port, cpu_limit, memory_limit, created_at 
-		FROM challenges WHERE is_active = 1`)
+		FROM challenges WHERE is_active = 1 AND IFNULL(event_id, 0) = ? AND `+visible, append([]interface{}{eventID}, args...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const eventColumns = `id, IFNULL(slug, ''), name, IFNULL(namespace, ''), start_time, end_time, freeze_time, paused_at,
+	IFNULL(mode, ''), share_instances, created_at`
+
+// CreateEvent crée un événement
+func CreateEvent(event *models.Event) error {
+	result, err := DB.Exec(`INSERT INTO events (slug, name, namespace, start_time, end_time, freeze_time, paused_at,
+		mode, share_instances)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		nullableString(event.Slug), event.Name, nullableString(event.Namespace),
+		event.StartTime.UTC(), event.EndTime.UTC(), utcOrNil(event.FreezeTime), utcOrNil(event.PausedAt),
+		nullableString(event.Mode), event.ShareInstances)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	event.ID = int(id)
+	return nil
+}
+
+// UpdateEvent enregistre le slug, le nom, le namespace, les dates, l'état de pause et
+// le mode de jeu d'un événement
+func UpdateEvent(event *models.Event) error {
+	_, err := DB.Exec(`UPDATE events SET slug = ?, name = ?, namespace = ?, start_time = ?, end_time = ?,
+		freeze_time = ?, paused_at = ?, mode = ?, share_instances = ? WHERE id = ?`,
+		nullableString(event.Slug), event.Name, nullableString(event.Namespace),
+		event.StartTime.UTC(), event.EndTime.UTC(), utcOrNil(event.FreezeTime), utcOrNil(event.PausedAt),
+		nullableString(event.Mode), event.ShareInstances, event.ID)
+	return err
+}
+
+// GetEventByID récupère un événement par son ID
+func GetEventByID(id int) (*models.Event, error) {
+	return scanEvent(DB.QueryRow("SELECT "+eventColumns+" FROM events WHERE id = ?", id))
+}
+
+// GetEventBySlug récupère un événement hébergé par son slug
+func GetEventBySlug(slug string) (*models.Event, error) {
+	return scanEvent(DB.QueryRow("SELECT "+eventColumns+" FROM events WHERE slug = ?", slug))
+}
+
+// GetCurrentEvent récupère l'événement de la plateforme (sans slug) le plus récemment
+// créé, nil si aucun n'est configuré
+func GetCurrentEvent() (*models.Event, error) {
+	return scanEvent(DB.QueryRow("SELECT " + eventColumns + " FROM events WHERE slug IS NULL ORDER BY id DESC LIMIT 1"))
+}
+
+// GetEvents récupère tous les événements, du plus récent au plus ancien
+func GetEvents() ([]models.Event, error) {
+	rows, err := DB.Query("SELECT " + eventColumns + " FROM events ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+
+	return events, rows.Err()
+}
+
+func scanEvent(row interface{ Scan(...interface{}) error }) (*models.Event, error) {
+	var event models.Event
+	var freezeTime, pausedAt sql.NullTime
+	var shareInstances sql.NullBool
+
+	err := row.Scan(&event.ID, &event.Slug, &event.Name, &event.Namespace, &event.StartTime, &event.EndTime, &freezeTime, &pausedAt,
+		&event.Mode, &shareInstances, &event.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if freezeTime.Valid {
+		event.FreezeTime = &freezeTime.Time
+	}
+	if pausedAt.Valid {
+		event.PausedAt = &pausedAt.Time
+	}
+	if shareInstances.Valid {
+		event.ShareInstances = &shareInstances.Bool
+	}
+	return &event, nil
+}
+
+// RegisterForEvent inscrit un joueur à un événement. Retourne false s'il était déjà inscrit.
+func RegisterForEvent(eventID, userID int) (bool, error) {
+	result, err := DB.Exec("INSERT OR IGNORE INTO event_registrations (event_id, user_id) VALUES (?, ?)", eventID, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// IsRegisteredForEvent indique si un joueur est inscrit à un événement
+func IsRegisteredForEvent(eventID, userID int) (bool, error) {
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM event_registrations WHERE event_id = ? AND user_id = ?)",
+		eventID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetEventRegistrations récupère les inscrits d'un événement, par ordre d'inscription
+func GetEventRegistrations(eventID int) ([]models.EventRegistration, error) {
+	rows, err := DB.Query(`SELECT er.event_id, er.user_id, u.username, u.team_id, er.registered_at
+		FROM event_registrations er
+		JOIN users u ON er.user_id = u.id
+		WHERE er.event_id = ?
+		ORDER BY er.registered_at ASC, er.id ASC`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	registrations := []models.EventRegistration{}
+	for rows.Next() {
+		var registration models.EventRegistration
+		var teamID sql.NullInt64
+		if err := rows.Scan(&registration.EventID, &registration.UserID, &registration.Username,
+			&teamID, &registration.RegisteredAt); err != nil {
+			return nil, err
+		}
+		if teamID.Valid {
+			id := int(teamID.Int64)
+			registration.TeamID = &id
+		}
+		registrations = append(registrations, registration)
+	}
+
+	return registrations, rows.Err()
+}
+
+// utcOrNil convertit une date optionnelle en UTC pour l'enregistrer
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+)
+
+// CreateFlag ajoute un flag supplémentaire à un challenge
+func CreateFlag(flag *models.Flag) error {
+	return createFlag(DB, flag)
+}
+
+func createFlag(conn execer, flag *models.Flag) error {
+	result, err := conn.Exec(`INSERT INTO flags (challenge_id, type, content, case_sensitive, points)
+		VALUES (?, ?, ?, ?, ?)`,
+		flag.ChallengeID, flag.Type, flag.Content, flag.CaseSensitive, flag.Points)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	flag.ID = int(id)
+	return nil
+}
+
+// GetFlagByID récupère un flag supplémentaire par son ID
+func GetFlagByID(id int) (*models.Flag, error) {
+	var flag models.Flag
+	err := DB.QueryRow(`SELECT id, challenge_id, type, content, case_sensitive, points FROM flags WHERE id = ?`, id).
+		Scan(&flag.ID, &flag.ChallengeID, &flag.Type, &flag.Content, &flag.CaseSensitive, &flag.Points)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// GetChallengeFlags récupère les flags supplémentaires d'un challenge, ceux qui
+// résolvent le challenge en premier
+func GetChallengeFlags(challengeID int) ([]models.Flag, error) {
+	rows, err := DB.Query(`SELECT id, challenge_id, type, content, case_sensitive, points
+		FROM flags WHERE challenge_id = ?
+		ORDER BY points > 0, id ASC`, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []models.Flag{}
+	for rows.Next() {
+		var flag models.Flag
+		if err := rows.Scan(&flag.ID, &flag.ChallengeID, &flag.Type, &flag.Content, &flag.CaseSensitive, &flag.Points); err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags, rows.Err()
+}
+
+// DeleteFlag supprime un flag supplémentaire et retire les points partiels déjà
+// crédités aux joueurs et équipes qui l'avaient validé
+func DeleteFlag(id int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteFlag(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteFlag(tx *sql.Tx, id int) error {
+	statements := []string{
+		`UPDATE users SET score = score - IFNULL((SELECT SUM(points) FROM flag_captures
+			WHERE flag_id = ? AND user_id = users.id), 0)`,
+		`UPDATE teams SET score = score - IFNULL((SELECT SUM(points) FROM flag_captures
+			WHERE flag_id = ? AND team_id = teams.id), 0)`,
+		"DELETE FROM flag_captures WHERE flag_id = ?",
+		// Les points d'un flag partiel sont retirés : sans flag_id, ses soumissions ne
+		// doivent pas passer pour des résolutions
+		"UPDATE submissions SET points_awarded = 0 WHERE flag_id = ? AND flag_id IN (SELECT id FROM flags WHERE points > 0)",
+		"UPDATE submissions SET flag_id = NULL WHERE flag_id = ?",
+		"DELETE FROM flags WHERE id = ?",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
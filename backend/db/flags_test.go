@@ -0,0 +1,80 @@
+package db
+
+import (
+	"backend/models"
+	"testing"
+)
+
+func TestPartialFlagIsCreditedOncePerTeam(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	team := &models.Team{Name: "pwners", InviteCode: "code", CaptainID: alice}
+	if err := CreateTeam(team); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if err := JoinTeam(bob, team.ID); err != nil {
+		t.Fatalf("JoinTeam: %v", err)
+	}
+
+	part := &models.Flag{ChallengeID: 1, Type: models.FlagStatic, Content: "CTF{part_one}", CaseSensitive: true, Points: 25}
+	if err := CreateFlag(part); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+
+	for i, userID := range []int{alice, bob} {
+		submission := &models.Submission{UserID: userID, ChallengeID: 1, TeamID: &team.ID, FlagID: &part.ID,
+			SubmittedFlag: part.Content, IsValid: true}
+		awarded, err := RecordSubmission(submission)
+		if err != nil {
+			t.Fatalf("RecordSubmission: %v", err)
+		}
+		if awarded != (i == 0) {
+			t.Errorf("submission %d awarded = %t", i, awarded)
+		}
+	}
+
+	solves, err := GetChallengeSolves(1, nil)
+	if err != nil {
+		t.Fatalf("GetChallengeSolves: %v", err)
+	}
+	if len(solves) != 0 {
+		t.Errorf("partial flag solved the challenge: %+v", solves)
+	}
+
+	team, err = GetTeamByID(team.ID)
+	if err != nil || team == nil {
+		t.Fatalf("GetTeamByID: %v", err)
+	}
+	if team.Score != 25 || userScore(t, alice) != 25 || userScore(t, bob) != 0 {
+		t.Errorf("team score = %d, alice = %d, bob = %d; want 25, 25, 0", team.Score, userScore(t, alice), userScore(t, bob))
+	}
+
+	if err := DeleteFlag(part.ID); err != nil {
+		t.Fatalf("DeleteFlag: %v", err)
+	}
+	team, _ = GetTeamByID(team.ID)
+	if team.Score != 0 || userScore(t, alice) != 0 {
+		t.Errorf("after DeleteFlag team score = %d, alice = %d; want 0", team.Score, userScore(t, alice))
+	}
+}
+
+func TestFullExtraFlagSolvesChallenge(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+
+	alternative := &models.Flag{ChallengeID: 1, Type: models.FlagRegex, Content: `CTF\{xss_[0-9]+\}`}
+	if err := CreateFlag(alternative); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+
+	submission := &models.Submission{UserID: alice, ChallengeID: 1, FlagID: &alternative.ID, SubmittedFlag: "CTF{xss_42}", IsValid: true}
+	awarded, err := RecordSubmission(submission)
+	if err != nil || !awarded {
+		t.Fatalf("RecordSubmission: awarded = %t, err = %v", awarded, err)
+	}
+	if submission.SolveRank != 1 || userScore(t, alice) != 75 {
+		t.Errorf("rank = %d, score = %d; want 1, 75", submission.SolveRank, userScore(t, alice))
+	}
+}
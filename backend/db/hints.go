@@ -0,0 +1,180 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+)
+
+var ErrInsufficientScore = errors.New("score too low to unlock hint")
+
+// CreateHint ajoute un indice à un challenge
+func CreateHint(hint *models.Hint) error {
+	return createHint(DB, hint)
+}
+
+func createHint(conn execer, hint *models.Hint) error {
+	result, err := conn.Exec("INSERT INTO hints (challenge_id, content, cost) VALUES (?, ?, ?)",
+		hint.ChallengeID, hint.Content, hint.Cost)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	hint.ID = int(id)
+	return nil
+}
+
+// GetHintByID récupère un indice par son ID
+func GetHintByID(id int) (*models.Hint, error) {
+	var hint models.Hint
+	err := DB.QueryRow("SELECT id, challenge_id, content, cost FROM hints WHERE id = ?", id).
+		Scan(&hint.ID, &hint.ChallengeID, &hint.Content, &hint.Cost)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &hint, nil
+}
+
+// GetChallengeHints récupère les indices d'un challenge sans leur contenu
+func GetChallengeHints(challengeID int) ([]models.Hint, error) {
+	return GetPlayerHints(challengeID, 0, nil)
+}
+
+// GetChallengeHintContents récupère les indices d'un challenge avec leur contenu (administration)
+func GetChallengeHintContents(challengeID int) ([]models.Hint, error) {
+	rows, err := DB.Query(`SELECT id, challenge_id, content, cost FROM hints
+		WHERE challenge_id = ? ORDER BY cost ASC, id ASC`, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hints := []models.Hint{}
+	for rows.Next() {
+		var hint models.Hint
+		if err := rows.Scan(&hint.ID, &hint.ChallengeID, &hint.Content, &hint.Cost); err != nil {
+			return nil, err
+		}
+		hints = append(hints, hint)
+	}
+
+	return hints, rows.Err()
+}
+
+// GetPlayerHints récupère les indices d'un challenge pour un joueur : le contenu
+// n'est renseigné que pour les indices débloqués par lui ou par son équipe
+func GetPlayerHints(challengeID, userID int, teamID *int) ([]models.Hint, error) {
+	rows, err := DB.Query(`SELECT h.id, h.challenge_id, h.content, h.cost,
+		EXISTS (SELECT 1 FROM hint_unlocks hu WHERE hu.hint_id = h.id AND (hu.user_id = ? OR hu.team_id = ?))
+		FROM hints h
+		WHERE h.challenge_id = ?
+		ORDER BY h.cost ASC, h.id ASC`, userID, teamID, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hints := []models.Hint{}
+	for rows.Next() {
+		var hint models.Hint
+		if err := rows.Scan(&hint.ID, &hint.ChallengeID, &hint.Content, &hint.Cost, &hint.Unlocked); err != nil {
+			return nil, err
+		}
+		if !hint.Unlocked {
+			hint.Content = ""
+		}
+		hints = append(hints, hint)
+	}
+
+	return hints, rows.Err()
+}
+
+// UnlockHint débloque un indice pour un joueur, ou pour son équipe si teamID est défini,
+// et déduit son coût du score correspondant. Un indice déjà débloqué n'est pas facturé
+// une seconde fois. Retourne le coût effectivement déduit.
+func UnlockHint(hint *models.Hint, userID int, teamID *int) (int, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var unlocked bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM hint_unlocks WHERE hint_id = ? AND (user_id = ? OR team_id = ?))`,
+		hint.ID, userID, teamID).Scan(&unlocked)
+	if err != nil {
+		return 0, err
+	}
+	if unlocked {
+		return 0, nil
+	}
+
+	var score int
+	if teamID != nil {
+		err = tx.QueryRow("SELECT score FROM teams WHERE id = ?", *teamID).Scan(&score)
+	} else {
+		err = tx.QueryRow("SELECT score FROM users WHERE id = ?", userID).Scan(&score)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if score < hint.Cost {
+		return 0, ErrInsufficientScore
+	}
+
+	if _, err := tx.Exec("INSERT INTO hint_unlocks (hint_id, user_id, team_id, cost) VALUES (?, ?, ?, ?)",
+		hint.ID, userID, teamID, hint.Cost); err != nil {
+		return 0, err
+	}
+
+	if teamID != nil {
+		_, err = tx.Exec("UPDATE teams SET score = score - ? WHERE id = ?", hint.Cost, *teamID)
+	} else {
+		_, err = tx.Exec("UPDATE users SET score = score - ? WHERE id = ?", hint.Cost, userID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return hint.Cost, tx.Commit()
+}
+
+// DeleteHint supprime un indice et rembourse les joueurs et équipes qui l'avaient débloqué
+func DeleteHint(id int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteHint(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteHint(tx *sql.Tx, id int) error {
+	statements := []string{
+		`UPDATE users SET score = score + IFNULL((SELECT SUM(cost) FROM hint_unlocks
+			WHERE hint_id = ? AND user_id = users.id AND team_id IS NULL), 0)`,
+		`UPDATE teams SET score = score + IFNULL((SELECT SUM(cost) FROM hint_unlocks
+			WHERE hint_id = ? AND team_id = teams.id), 0)`,
+		"DELETE FROM hint_unlocks WHERE hint_id = ?",
+		"DELETE FROM hints WHERE id = ?",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"backend/models"
+	"testing"
+)
+
+func userScore(t *testing.T, userID int) int {
+	t.Helper()
+	user, err := GetUserByID(userID)
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByID(%d): %v", userID, err)
+	}
+	return user.Score
+}
+
+func TestUnlockHintChargesOnce(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+
+	hint := &models.Hint{ChallengeID: 1, Content: "regarde le header", Cost: 30}
+	if err := CreateHint(hint); err != nil {
+		t.Fatalf("CreateHint: %v", err)
+	}
+
+	if _, err := UnlockHint(hint, alice, nil); err != ErrInsufficientScore {
+		t.Fatalf("UnlockHint with empty score: err = %v, want ErrInsufficientScore", err)
+	}
+
+	if _, err := RecordSubmission(&models.Submission{UserID: alice, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+
+	for i, want := range []int{30, 0} {
+		cost, err := UnlockHint(hint, alice, nil)
+		if err != nil {
+			t.Fatalf("UnlockHint #%d: %v", i+1, err)
+		}
+		if cost != want {
+			t.Errorf("UnlockHint #%d cost = %d, want %d", i+1, cost, want)
+		}
+	}
+	if score := userScore(t, alice); score != 45 {
+		t.Errorf("score after unlock = %d, want 45", score)
+	}
+
+	hints, err := GetPlayerHints(1, alice, nil)
+	if err != nil || len(hints) != 1 {
+		t.Fatalf("GetPlayerHints: %v (%d hints)", err, len(hints))
+	}
+	if !hints[0].Unlocked || hints[0].Content != hint.Content {
+		t.Errorf("unlocked hint = %+v, want content visible", hints[0])
+	}
+
+	if err := DeleteHint(hint.ID); err != nil {
+		t.Fatalf("DeleteHint: %v", err)
+	}
+	if score := userScore(t, alice); score != 75 {
+		t.Errorf("score after refund = %d, want 75", score)
+	}
+}
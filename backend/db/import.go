@@ -0,0 +1,79 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+)
+
+// execer est implémenté par *sql.DB et *sql.Tx : les écritures qui l'acceptent
+// peuvent s'exécuter seules ou dans une transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// ImportChallenge applique un plan d'import dans une seule transaction : création ou
+// mise à jour du challenge, puis de ses flags, indices et pièces jointes. storeFiles
+// reçoit l'ID du challenge, stocke les nouveaux fichiers et retourne les pièces
+// jointes à enregistrer. En cas d'erreur, rien n'est écrit en base.
+func ImportChallenge(plan *models.ChallengeImportPlan, storeFiles func(challengeID int) ([]models.Attachment, error)) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	challenge := plan.Challenge
+	value := challenge.Points
+	if challenge.ID == 0 {
+		if challenge.ID, err = createChallenge(tx, challenge); err != nil {
+			return err
+		}
+	} else if value, err = updateChallenge(tx, challenge); err != nil {
+		return err
+	}
+
+	for _, flag := range plan.StaleFlags {
+		if err := deleteFlag(tx, flag.ID); err != nil {
+			return err
+		}
+	}
+	for _, flag := range plan.NewFlags {
+		flag.ChallengeID = challenge.ID
+		if err := createFlag(tx, &flag); err != nil {
+			return err
+		}
+	}
+	for _, hint := range plan.StaleHints {
+		if err := deleteHint(tx, hint.ID); err != nil {
+			return err
+		}
+	}
+	for _, hint := range plan.NewHints {
+		hint.ChallengeID = challenge.ID
+		if err := createHint(tx, &hint); err != nil {
+			return err
+		}
+	}
+	for _, attachment := range plan.StaleAttachments {
+		if _, err := tx.Exec("DELETE FROM attachments WHERE id = ?", attachment.ID); err != nil {
+			return err
+		}
+	}
+
+	attachments, err := storeFiles(challenge.ID)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := createAttachment(tx, &attachment); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	challenge.Points = value
+	return nil
+}
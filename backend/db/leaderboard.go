@@ -0,0 +1,331 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// scopedChallenges restreint les requêtes de score aux challenges de l'événement
+// @event, ou à ceux de la plateforme (hors événements hébergés) si @event vaut 0.
+// Chaque événement a ainsi son propre classement. La valeur d'un challenge
+// dynamique est celle qu'il avait à @at, d'après ses résolutions antérieures (même
+// formule que DynamicValue) : un classement gelé ne bouge pas quand elle décroît.
+const scopedChallenges = `WITH scoped_counts AS (
+		SELECT id, points, IFNULL(scoring_type, 'static') = 'dynamic' AND IFNULL(decay, 0) > 0 AS dynamic,
+			IFNULL(initial_value, 0) AS initial, IFNULL(minimum_value, 0) AS minimum, IFNULL(decay, 0) AS decay,
+			MAX((SELECT COUNT(*) FROM challenge_solves WHERE challenge_id = challenges.id AND solved_at < @at) - 1, 0) AS n
+		FROM challenges WHERE IFNULL(event_id, 0) = @event
+	), scoped_values AS (
+		SELECT id, points, dynamic, minimum,
+			CAST(minimum - initial AS REAL) / (decay * decay) * (n * n) + initial AS value
+		FROM scoped_counts
+	), scoped_challenges AS (
+		SELECT id, CASE WHEN dynamic
+			THEN MAX(minimum, CAST(value AS INTEGER) + (value > CAST(value AS INTEGER)))
+			ELSE points END AS points
+		FROM scoped_values
+	)
+	`
+
+// scoredLeaderboardQuery recalcule le score des joueurs à partir des résolutions,
+// flags partiels et indices antérieurs à @at. Pour un événement, seuls ses inscrits
+// et ses challenges sont pris en compte.
+const scoredLeaderboardQuery = scopedChallenges + `SELECT u.id, u.username,
+		IFNULL((SELECT SUM(c.points + IFNULL(cs.bonus, 0)) FROM challenge_solves cs
+			JOIN scoped_challenges c ON cs.challenge_id = c.id
+			WHERE cs.user_id = u.id AND cs.solved_at < @at), 0)
+		+ IFNULL((SELECT SUM(fc.points) FROM flag_captures fc
+			JOIN flags f ON fc.flag_id = f.id
+			JOIN scoped_challenges c ON f.challenge_id = c.id
+			WHERE fc.user_id = u.id AND fc.captured_at < @at), 0)
+		- IFNULL((SELECT SUM(hu.cost) FROM hint_unlocks hu
+			JOIN hints h ON hu.hint_id = h.id
+			JOIN scoped_challenges c ON h.challenge_id = c.id
+			WHERE hu.user_id = u.id AND hu.team_id IS NULL AND hu.unlocked_at < @at), 0) AS computed_score,
+		(SELECT COUNT(*) FROM challenge_solves cs JOIN scoped_challenges c ON cs.challenge_id = c.id
+			WHERE cs.user_id = u.id AND cs.solved_at < @at),
+		(SELECT MAX(cs.solved_at) FROM challenge_solves cs JOIN scoped_challenges c ON cs.challenge_id = c.id
+			WHERE cs.user_id = u.id AND cs.solved_at < @at) AS last_solve
+	FROM users u
+	WHERE u.role != 'admin'
+	AND (@event = 0 OR u.id IN (SELECT user_id FROM event_registrations WHERE event_id = @event))
+	ORDER BY computed_score DESC, last_solve IS NULL, last_solve ASC, u.id ASC
+	LIMIT @limit OFFSET @offset`
+
+// scoreboardUntil retourne la borne des résolutions comptées : la date de gel, ou
+// une date lointaine pour le classement en direct
+func scoreboardUntil(frozenAt *time.Time) string {
+	if frozenAt != nil {
+		return formatTimestamp(*frozenAt)
+	}
+	return "9999-12-31 23:59:59"
+}
+
+// GetLeaderboard récupère une page du classement de la plateforme et le nombre total
+// de joueurs classés. Si frozenAt est défini, le classement est celui de cette date.
+func GetLeaderboard(limit, offset int, frozenAt *time.Time) ([]models.LeaderboardEntry, int, error) {
+	return getLeaderboard(0, limit, offset, frozenAt)
+}
+
+// GetEventLeaderboard récupère une page du classement des inscrits d'un événement,
+// limité à ses challenges, et le nombre total d'inscrits
+func GetEventLeaderboard(eventID, limit, offset int, frozenAt *time.Time) ([]models.LeaderboardEntry, int, error) {
+	return getLeaderboard(eventID, limit, offset, frozenAt)
+}
+
+func getLeaderboard(eventID, limit, offset int, frozenAt *time.Time) ([]models.LeaderboardEntry, int, error) {
+	var total int
+	err := DB.QueryRow(`SELECT COUNT(*) FROM users WHERE role != 'admin'
+		AND (@event = 0 OR id IN (SELECT user_id FROM event_registrations WHERE event_id = @event))`,
+		sql.Named("event", eventID)).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Les scores tenus à jour dans users.score comptent aussi les événements hébergés :
+	// le classement est toujours recalculé sur les challenges de sa portée
+	rows, err := DB.Query(scoredLeaderboardQuery, sql.Named("event", eventID), sql.Named("at", scoreboardUntil(frozenAt)),
+		sql.Named("limit", limit), sql.Named("offset", offset))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.LeaderboardEntry{}
+	for rows.Next() {
+		var entry models.LeaderboardEntry
+		var lastSolve sql.NullString
+
+		if err := rows.Scan(&entry.UserID, &entry.Username, &entry.Score, &entry.Solves, &lastSolve); err != nil {
+			log.Printf("Error scanning leaderboard entry: %v", err)
+			continue
+		}
+
+		if lastSolve.Valid {
+			solvedAt, err := parseTimestamp(lastSolve.String)
+			if err != nil {
+				log.Printf("Warning: failed to parse solved_at date: %v", err)
+			} else {
+				entry.LastSolveAt = &solvedAt
+			}
+		}
+
+		entry.Rank = offset + len(entries) + 1
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// GetTopScoreSeries construit l'évolution du score des n premiers joueurs à partir de leurs
+// résolutions et des indices qu'ils ont débloqués, jusqu'à frozenAt s'il est défini
+func GetTopScoreSeries(n int, frozenAt *time.Time) ([]models.ScoreSeries, error) {
+	return getTopScoreSeries(0, n, frozenAt)
+}
+
+// GetEventTopScoreSeries construit l'évolution du score des n premiers inscrits d'un
+// événement sur ses challenges
+func GetEventTopScoreSeries(eventID, n int, frozenAt *time.Time) ([]models.ScoreSeries, error) {
+	return getTopScoreSeries(eventID, n, frozenAt)
+}
+
+func getTopScoreSeries(eventID, n int, frozenAt *time.Time) ([]models.ScoreSeries, error) {
+	top, _, err := getLeaderboard(eventID, n, 0, frozenAt)
+	if err != nil {
+		return nil, err
+	}
+
+	series := []models.ScoreSeries{}
+	for _, entry := range top {
+		// Les flags partiels augmentent le score, les indices débloqués à titre
+		// individuel le font baisser
+		rows, err := DB.Query(scopedChallenges+`SELECT at, delta FROM (
+				SELECT cs.solved_at AS at, c.points + IFNULL(cs.bonus, 0) AS delta
+				FROM challenge_solves cs
+				JOIN scoped_challenges c ON cs.challenge_id = c.id
+				WHERE cs.user_id = @user
+				UNION ALL
+				SELECT fc.captured_at, fc.points
+				FROM flag_captures fc
+				JOIN flags f ON fc.flag_id = f.id
+				JOIN scoped_challenges c ON f.challenge_id = c.id
+				WHERE fc.user_id = @user
+				UNION ALL
+				SELECT hu.unlocked_at, -hu.cost
+				FROM hint_unlocks hu
+				JOIN hints h ON hu.hint_id = h.id
+				JOIN scoped_challenges c ON h.challenge_id = c.id
+				WHERE hu.user_id = @user AND hu.team_id IS NULL
+			)
+			WHERE at < @at
+			ORDER BY at ASC`, sql.Named("event", eventID), sql.Named("user", entry.UserID),
+			sql.Named("at", scoreboardUntil(frozenAt)))
+		if err != nil {
+			return nil, err
+		}
+
+		s := models.ScoreSeries{
+			UserID:   entry.UserID,
+			Username: entry.Username,
+			Score:    entry.Score,
+			Points:   []models.ScorePoint{},
+		}
+		cumulative := 0
+		for rows.Next() {
+			var solvedAtStr string
+			var points int
+			if err := rows.Scan(&solvedAtStr, &points); err != nil {
+				log.Printf("Error scanning solve: %v", err)
+				continue
+			}
+
+			solvedAt, err := parseTimestamp(solvedAtStr)
+			if err != nil {
+				log.Printf("Warning: failed to parse solved_at date: %v", err)
+			}
+			cumulative += points
+			s.Points = append(s.Points, models.ScorePoint{Time: solvedAt, Score: cumulative})
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		series = append(series, s)
+	}
+
+	return series, nil
+}
+
+// scoredTeamLeaderboardQuery recalcule le score des équipes à partir des résolutions,
+// flags partiels et indices antérieurs à @at. Pour un événement, seules les équipes
+// ayant un membre inscrit et ses challenges sont prises en compte.
+const scoredTeamLeaderboardQuery = scopedChallenges + `SELECT t.id, t.name,
+		IFNULL((SELECT SUM(c.points + IFNULL(cs.bonus, 0)) FROM challenge_solves cs
+			JOIN scoped_challenges c ON cs.challenge_id = c.id
+			WHERE cs.team_id = t.id AND cs.solved_at < @at), 0)
+		+ IFNULL((SELECT SUM(fc.points) FROM flag_captures fc
+			JOIN flags f ON fc.flag_id = f.id
+			JOIN scoped_challenges c ON f.challenge_id = c.id
+			WHERE fc.team_id = t.id AND fc.captured_at < @at), 0)
+		- IFNULL((SELECT SUM(hu.cost) FROM hint_unlocks hu
+			JOIN hints h ON hu.hint_id = h.id
+			JOIN scoped_challenges c ON h.challenge_id = c.id
+			WHERE hu.team_id = t.id AND hu.unlocked_at < @at), 0) AS computed_score,
+		(SELECT COUNT(*) FROM challenge_solves cs JOIN scoped_challenges c ON cs.challenge_id = c.id
+			WHERE cs.team_id = t.id AND cs.solved_at < @at),
+		(SELECT MAX(cs.solved_at) FROM challenge_solves cs JOIN scoped_challenges c ON cs.challenge_id = c.id
+			WHERE cs.team_id = t.id AND cs.solved_at < @at) AS last_solve,
+		(SELECT COUNT(*) FROM users u WHERE u.team_id = t.id)
+	FROM teams t
+	WHERE @event = 0 OR t.id IN (` + registeredTeams + `)
+	ORDER BY computed_score DESC, last_solve IS NULL, last_solve ASC, t.id ASC
+	LIMIT @limit OFFSET @offset`
+
+// registeredTeams sélectionne les équipes dont au moins un membre est inscrit à l'événement @event
+const registeredTeams = `SELECT u.team_id FROM event_registrations er
+	JOIN users u ON er.user_id = u.id
+	WHERE er.event_id = @event AND u.team_id IS NOT NULL`
+
+// GetTeamLeaderboard récupère une page du classement des équipes de la plateforme et le
+// nombre total d'équipes. Si frozenAt est défini, le classement est celui de cette date.
+func GetTeamLeaderboard(limit, offset int, frozenAt *time.Time) ([]models.TeamLeaderboardEntry, int, error) {
+	return getTeamLeaderboard(0, limit, offset, frozenAt)
+}
+
+// GetEventTeamLeaderboard récupère une page du classement des équipes d'un événement
+func GetEventTeamLeaderboard(eventID, limit, offset int, frozenAt *time.Time) ([]models.TeamLeaderboardEntry, int, error) {
+	return getTeamLeaderboard(eventID, limit, offset, frozenAt)
+}
+
+func getTeamLeaderboard(eventID, limit, offset int, frozenAt *time.Time) ([]models.TeamLeaderboardEntry, int, error) {
+	var total int
+	err := DB.QueryRow(`SELECT COUNT(*) FROM teams WHERE @event = 0 OR id IN (`+registeredTeams+`)`,
+		sql.Named("event", eventID)).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := DB.Query(scoredTeamLeaderboardQuery, sql.Named("event", eventID), sql.Named("at", scoreboardUntil(frozenAt)),
+		sql.Named("limit", limit), sql.Named("offset", offset))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.TeamLeaderboardEntry{}
+	for rows.Next() {
+		var entry models.TeamLeaderboardEntry
+		var lastSolve sql.NullString
+
+		if err := rows.Scan(&entry.TeamID, &entry.Name, &entry.Score, &entry.Solves, &lastSolve, &entry.Members); err != nil {
+			log.Printf("Error scanning team leaderboard entry: %v", err)
+			continue
+		}
+
+		if lastSolve.Valid {
+			solvedAt, err := parseTimestamp(lastSolve.String)
+			if err != nil {
+				log.Printf("Warning: failed to parse solved_at date: %v", err)
+			} else {
+				entry.LastSolveAt = &solvedAt
+			}
+		}
+
+		entry.Rank = offset + len(entries) + 1
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// GetScopedScore retourne le score d'un joueur, ou de son équipe si teamID est défini,
+// obtenu sur les challenges de la plateforme (eventID 0) ou d'un événement hébergé.
+// Si frozenAt est défini, le score est celui de cette date.
+func GetScopedScore(eventID, userID int, teamID *int, frozenAt *time.Time) (int, error) {
+	var score int
+	owner, ownerID, hintOwner := "user_id", userID, "hu.user_id = @owner AND hu.team_id IS NULL"
+	if teamID != nil {
+		owner, ownerID, hintOwner = "team_id", *teamID, "hu.team_id = @owner"
+	}
+	err := DB.QueryRow(scopedChallenges+`SELECT
+		IFNULL((SELECT SUM(c.points + IFNULL(cs.bonus, 0)) FROM challenge_solves cs
+			JOIN scoped_challenges c ON cs.challenge_id = c.id
+			WHERE cs.`+owner+` = @owner AND cs.solved_at < @at), 0)
+		+ IFNULL((SELECT SUM(fc.points) FROM flag_captures fc
+			JOIN flags f ON fc.flag_id = f.id
+			JOIN scoped_challenges c ON f.challenge_id = c.id
+			WHERE fc.`+owner+` = @owner AND fc.captured_at < @at), 0)
+		- IFNULL((SELECT SUM(hu.cost) FROM hint_unlocks hu
+			JOIN hints h ON hu.hint_id = h.id
+			JOIN scoped_challenges c ON h.challenge_id = c.id
+			WHERE `+hintOwner+` AND hu.unlocked_at < @at), 0)`,
+		sql.Named("event", eventID), sql.Named("owner", ownerID), sql.Named("at", scoreboardUntil(frozenAt))).Scan(&score)
+	return score, err
+}
+
+// GetChallengeValuesAt retourne la valeur et le nombre de résolutions des challenges
+// de l'événement eventID (de la plateforme si eventID vaut 0) tels qu'ils étaient à la date at
+func GetChallengeValuesAt(eventID int, at time.Time) (points, solves map[int]int, err error) {
+	rows, err := DB.Query(scopedChallenges+`SELECT c.id, c.points,
+		(SELECT COUNT(*) FROM challenge_solves cs WHERE cs.challenge_id = c.id AND cs.solved_at < @at)
+		FROM scoped_challenges c`,
+		sql.Named("event", eventID), sql.Named("at", scoreboardUntil(&at)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	points, solves = make(map[int]int), make(map[int]int)
+	for rows.Next() {
+		var id, value, count int
+		if err := rows.Scan(&id, &value, &count); err != nil {
+			return nil, nil, err
+		}
+		points[id], solves[id] = value, count
+	}
+	return points, solves, rows.Err()
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"backend/models"
+	"testing"
+	"time"
+)
+
+func TestFrozenLeaderboardIgnoresLateSolves(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	for _, userID := range []int{alice, bob} {
+		if _, err := RecordSubmission(&models.Submission{UserID: userID, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}); err != nil {
+			t.Fatalf("RecordSubmission: %v", err)
+		}
+	}
+
+	freeze := time.Now().UTC().Add(-time.Hour)
+	if _, err := DB.Exec("UPDATE challenge_solves SET solved_at = ? WHERE user_id = ?", formatTimestamp(freeze.Add(-time.Hour)), alice); err != nil {
+		t.Fatalf("backdate solve: %v", err)
+	}
+
+	live, _, err := GetLeaderboard(10, 0, nil)
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	frozen, _, err := GetLeaderboard(10, 0, &freeze)
+	if err != nil {
+		t.Fatalf("GetLeaderboard frozen: %v", err)
+	}
+
+	scores := func(entries []models.LeaderboardEntry) map[int]int {
+		byUser := make(map[int]int)
+		for _, entry := range entries {
+			byUser[entry.UserID] = entry.Score
+		}
+		return byUser
+	}
+	if got := scores(live); got[alice] != 75 || got[bob] != 75 {
+		t.Errorf("live scores = %v, want 75 each", got)
+	}
+	if got := scores(frozen); got[alice] != 75 || got[bob] != 0 {
+		t.Errorf("frozen scores = %v, want alice 75 and bob 0", got)
+	}
+	if frozen[0].UserID != alice || frozen[0].Solves != 1 {
+		t.Errorf("frozen leader = %+v, want alice with 1 solve", frozen[0])
+	}
+
+	series, err := GetTopScoreSeries(2, &freeze)
+	if err != nil {
+		t.Fatalf("GetTopScoreSeries: %v", err)
+	}
+	for _, s := range series {
+		if s.UserID == bob && len(s.Points) != 0 {
+			t.Errorf("frozen series shows bob's late solve: %+v", s.Points)
+		}
+	}
+}
+
+func TestFrozenLeaderboardKeepsDynamicValue(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	carol := createTestUser(t, "carol")
+
+	scoring := &models.UpdateChallengeScoringRequest{ScoringType: models.ScoringDynamic, InitialValue: 100, MinimumValue: 10, Decay: 2}
+	if _, err := UpdateChallengeScoring(1, scoring); err != nil {
+		t.Fatalf("UpdateChallengeScoring: %v", err)
+	}
+
+	// Alice résout avant le gel, Bob et Carol après : la valeur tombe de 100 à 10
+	freeze := time.Now().UTC().Add(-time.Hour)
+	for _, userID := range []int{alice, bob, carol} {
+		if _, err := RecordSubmission(&models.Submission{UserID: userID, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}); err != nil {
+			t.Fatalf("RecordSubmission: %v", err)
+		}
+	}
+	if _, err := DB.Exec("UPDATE challenge_solves SET solved_at = ? WHERE user_id = ?", formatTimestamp(freeze.Add(-time.Hour)), alice); err != nil {
+		t.Fatalf("backdate solve: %v", err)
+	}
+
+	live, _, err := GetLeaderboard(10, 0, nil)
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	if live[0].Score != 10 {
+		t.Errorf("live score = %d, want the decayed value 10", live[0].Score)
+	}
+
+	frozen, _, err := GetLeaderboard(10, 0, &freeze)
+	if err != nil {
+		t.Fatalf("GetLeaderboard frozen: %v", err)
+	}
+	if frozen[0].UserID != alice || frozen[0].Score != 100 {
+		t.Errorf("frozen leader = %+v, want alice with 100 points", frozen[0])
+	}
+	if score, err := GetScopedScore(0, alice, nil, &freeze); err != nil || score != 100 {
+		t.Errorf("GetScopedScore frozen = %d, %v, want 100", score, err)
+	}
+
+	points, solves, err := GetChallengeValuesAt(0, freeze)
+	if err != nil {
+		t.Fatalf("GetChallengeValuesAt: %v", err)
+	}
+	if points[1] != 100 || solves[1] != 1 {
+		t.Errorf("challenge 1 at freeze = %d points, %d solves, want 100 and 1", points[1], solves[1])
+	}
+
+	if late, err := GetUserSolves(bob, &freeze); err != nil || len(late) != 0 {
+		t.Errorf("GetUserSolves(bob) frozen = %+v, %v, want no solve", late, err)
+	}
+	if all, err := GetChallengeSolves(1, &freeze); err != nil || len(all) != 1 || all[0].UserID != alice {
+		t.Errorf("GetChallengeSolves frozen = %+v, %v, want alice's solve only", all, err)
+	}
+}
+
+func TestEventLeaderboardIsScopedToEvent(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	event := &models.Event{Slug: "weekly", Name: "Weekly", Namespace: "ctf-weekly",
+		StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)}
+	if err := CreateEvent(event); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	challengeID, err := CreateChallenge(&models.Challenge{Name: "Weekly rev", Description: "d", Category: "Reverse",
+		Difficulty: "easy", Points: 40, Flag: "CTF{weekly}", ScoringType: models.ScoringStatic, IsActive: true, EventID: &event.ID})
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+
+	if created, err := RegisterForEvent(event.ID, alice); err != nil || !created {
+		t.Fatalf("RegisterForEvent = %t, %v", created, err)
+	}
+	if created, _ := RegisterForEvent(event.ID, alice); created {
+		t.Error("second registration was created")
+	}
+
+	// Bob n'est pas inscrit : ses résolutions ne figurent pas au classement de l'événement
+	for _, solve := range []struct{ userID, challengeID int }{{alice, 1}, {alice, challengeID}, {bob, challengeID}} {
+		submission := &models.Submission{UserID: solve.userID, ChallengeID: solve.challengeID, SubmittedFlag: "flag", IsValid: true}
+		if _, err := RecordSubmission(submission); err != nil {
+			t.Fatalf("RecordSubmission: %v", err)
+		}
+	}
+
+	entries, total, err := GetEventLeaderboard(event.ID, 10, 0, nil)
+	if err != nil {
+		t.Fatalf("GetEventLeaderboard: %v", err)
+	}
+	if total != 1 || len(entries) != 1 || entries[0].UserID != alice || entries[0].Score != 40 || entries[0].Solves != 1 {
+		t.Errorf("event leaderboard = %+v (total %d), want alice with 40 points", entries, total)
+	}
+
+	platform, _, err := GetLeaderboard(10, 0, nil)
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	// Le classement de la plateforme ignore les challenges des événements hébergés
+	if platform[0].UserID != alice || platform[0].Score != 75 || platform[0].Solves != 1 {
+		t.Errorf("platform leader = %+v, want alice with 75 points", platform[0])
+	}
+	if score, err := GetScopedScore(0, bob, nil, nil); err != nil || score != 0 {
+		t.Errorf("bob's platform score = %d, %v, want 0", score, err)
+	}
+
+	challenges, err := GetAllChallenges(event.ID)
+	if err != nil || len(challenges) != 1 || challenges[0].ID != challengeID {
+		t.Errorf("event challenges = %+v, %v", challenges, err)
+	}
+}
@@ -0,0 +1,43 @@
+package db
+
+// ReservePort réserve un port NodePort pour une instance. L'unicité de la clé
+// primaire garantit qu'un port ne peut être réservé qu'une seule fois ;
+// retourne false si le port est déjà pris.
+func ReservePort(port int, podName string) (bool, error) {
+	result, err := DB.Exec("INSERT OR IGNORE INTO port_allocations (port, pod_name) VALUES (?, ?)", port, podName)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetAllocatedPorts récupère les ports réservés dans une plage
+func GetAllocatedPorts(min, max int) (map[int]bool, error) {
+	rows, err := DB.Query("SELECT port FROM port_allocations WHERE port BETWEEN ? AND ?", min, max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ports := make(map[int]bool)
+	for rows.Next() {
+		var port int
+		if err := rows.Scan(&port); err != nil {
+			return nil, err
+		}
+		ports[port] = true
+	}
+
+	return ports, rows.Err()
+}
+
+// ReleasePort libère le port réservé par une instance
+func ReleasePort(podName string) error {
+	_, err := DB.Exec("DELETE FROM port_allocations WHERE pod_name = ?", podName)
+	return err
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+)
+
+// GetChallengeUnlock récupère les conditions de déblocage d'un challenge, prérequis
+// désactivés compris. Retourne nil si le challenge n'existe pas.
+func GetChallengeUnlock(challengeID int) (*models.ChallengeUnlock, error) {
+	unlock := &models.ChallengeUnlock{Prerequisites: []int{}}
+	err := DB.QueryRow("SELECT IFNULL(unlock_mode, 'all'), IFNULL(unlock_score, 0) FROM challenges WHERE id = ?",
+		challengeID).Scan(&unlock.Mode, &unlock.MinScore)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rows, err := DB.Query(`SELECT prerequisite_id FROM challenge_prerequisites
+		WHERE challenge_id = ? ORDER BY prerequisite_id`, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		unlock.Prerequisites = append(unlock.Prerequisites, id)
+	}
+	return unlock, rows.Err()
+}
+
+// GetChallengeUnlocks récupère les conditions de déblocage de tous les challenges qui
+// en ont, indexées par challenge. Les prérequis désactivés sont ignorés : ils ne
+// peuvent plus être résolus.
+func GetChallengeUnlocks() (map[int]*models.ChallengeUnlock, error) {
+	rows, err := DB.Query(`SELECT id, IFNULL(unlock_mode, 'all'), IFNULL(unlock_score, 0) FROM challenges
+		WHERE IFNULL(unlock_score, 0) > 0 OR id IN (SELECT challenge_id FROM challenge_prerequisites)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unlocks := make(map[int]*models.ChallengeUnlock)
+	for rows.Next() {
+		var id int
+		unlock := &models.ChallengeUnlock{Prerequisites: []int{}}
+		if err := rows.Scan(&id, &unlock.Mode, &unlock.MinScore); err != nil {
+			return nil, err
+		}
+		unlocks[id] = unlock
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prerequisites, err := DB.Query(`SELECT cp.challenge_id, cp.prerequisite_id FROM challenge_prerequisites cp
+		JOIN challenges p ON cp.prerequisite_id = p.id
+		WHERE p.is_active = 1
+		ORDER BY cp.challenge_id, cp.prerequisite_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer prerequisites.Close()
+
+	for prerequisites.Next() {
+		var challengeID, prerequisiteID int
+		if err := prerequisites.Scan(&challengeID, &prerequisiteID); err != nil {
+			return nil, err
+		}
+		if unlock, ok := unlocks[challengeID]; ok {
+			unlock.Prerequisites = append(unlock.Prerequisites, prerequisiteID)
+		}
+	}
+	return unlocks, prerequisites.Err()
+}
+
+// SetChallengeUnlock remplace les conditions de déblocage d'un challenge. Si check
+// n'est pas nil, il reçoit, dans la même transaction, toutes les relations de
+// prérequis après le remplacement (pour chaque challenge, les challenges à résoudre
+// avant lui) : une erreur de check annule la modification.
+func SetChallengeUnlock(challengeID int, unlock *models.ChallengeUnlock, check func(graph map[int][]int) error) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE challenges SET unlock_mode = ?, unlock_score = ? WHERE id = ?",
+		unlock.Mode, unlock.MinScore, challengeID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM challenge_prerequisites WHERE challenge_id = ?", challengeID); err != nil {
+		return err
+	}
+	for _, prerequisiteID := range unlock.Prerequisites {
+		if _, err := tx.Exec("INSERT INTO challenge_prerequisites (challenge_id, prerequisite_id) VALUES (?, ?)",
+			challengeID, prerequisiteID); err != nil {
+			return err
+		}
+	}
+
+	if check != nil {
+		graph, err := prerequisiteGraph(tx)
+		if err != nil {
+			return err
+		}
+		if err := check(graph); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// prerequisiteGraph retourne toutes les relations de prérequis vues par tx
+func prerequisiteGraph(tx *sql.Tx) (map[int][]int, error) {
+	rows, err := tx.Query("SELECT challenge_id, prerequisite_id FROM challenge_prerequisites")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	graph := make(map[int][]int)
+	for rows.Next() {
+		var challengeID, prerequisiteID int
+		if err := rows.Scan(&challengeID, &prerequisiteID); err != nil {
+			return nil, err
+		}
+		graph[challengeID] = append(graph[challengeID], prerequisiteID)
+	}
+	return graph, rows.Err()
+}
+
+// GetSolvedChallengeIDs retourne les challenges résolus par un joueur ou par son équipe
+func GetSolvedChallengeIDs(userID int, teamID *int) (map[int]bool, error) {
+	rows, err := DB.Query("SELECT DISTINCT challenge_id FROM challenge_solves WHERE user_id = ? OR team_id = ?",
+		userID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	solved := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		solved[id] = true
+	}
+	return solved, rows.Err()
+}
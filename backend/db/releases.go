@@ -0,0 +1,88 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"time"
+)
+
+// VisibleChallengeFilter retourne la condition SQL, et ses arguments, qui restreint
+// une requête sur la table challenges aux challenges publiés à la date now
+func VisibleChallengeFilter(now time.Time) (string, []interface{}) {
+	return "(visible_from IS NULL OR visible_from <= ?) AND (visible_until IS NULL OR visible_until > ?)",
+		[]interface{}{now.UTC(), now.UTC()}
+}
+
+// releasePending indique si la publication d'un challenge reste à annoncer : seules
+// les publications programmées dans le futur le sont
+func releasePending(visibleFrom *time.Time, now time.Time) bool {
+	return visibleFrom != nil && visibleFrom.After(now)
+}
+
+// SetChallengeSchedule remplace la fenêtre de publication d'un challenge. Une
+// publication future sera annoncée lorsqu'elle aura lieu.
+func SetChallengeSchedule(challengeID int, visibleFrom, visibleUntil *time.Time) error {
+	_, err := DB.Exec("UPDATE challenges SET visible_from = ?, visible_until = ?, release_announced = ? WHERE id = ?",
+		utcOrNil(visibleFrom), utcOrNil(visibleUntil), !releasePending(visibleFrom, time.Now()), challengeID)
+	return err
+}
+
+// GetUpcomingReleases récupère les challenges actifs dont la publication est
+// programmée après now, par date de publication
+func GetUpcomingReleases(now time.Time) ([]models.Challenge, error) {
+	return getScheduledChallenges(`WHERE is_active = 1 AND visible_from > ?
+		ORDER BY visible_from, id`, now.UTC())
+}
+
+// GetPendingReleases récupère les challenges actifs publiés depuis now dont la
+// publication n'a pas encore été annoncée. Ceux d'un événement hébergé qui n'a pas
+// commencé attendent son ouverture.
+func GetPendingReleases(now time.Time) ([]models.Challenge, error) {
+	return getScheduledChallenges(`WHERE is_active = 1 AND release_announced = 0
+		AND visible_from <= ? AND (visible_until IS NULL OR visible_until > ?)
+		AND (event_id IS NULL OR event_id IN (SELECT id FROM events WHERE start_time <= ?))
+		ORDER BY visible_from, id`, now.UTC(), now.UTC(), now.UTC())
+}
+
+func getScheduledChallenges(filter string, args ...interface{}) ([]models.Challenge, error) {
+	rows, err := DB.Query(`SELECT id, name, category, points, event_id, visible_from, visible_until
+		FROM challenges `+filter, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	challenges := []models.Challenge{}
+	for rows.Next() {
+		var challenge models.Challenge
+		var eventID sql.NullInt64
+		var visibleFrom, visibleUntil sql.NullTime
+		err := rows.Scan(&challenge.ID, &challenge.Name, &challenge.Category, &challenge.Points,
+			&eventID, &visibleFrom, &visibleUntil)
+		if err != nil {
+			return nil, err
+		}
+
+		challenge.EventID = nullableInt(eventID)
+		if visibleFrom.Valid {
+			challenge.VisibleFrom = &visibleFrom.Time
+		}
+		if visibleUntil.Valid {
+			challenge.VisibleUntil = &visibleUntil.Time
+		}
+		challenge.IsActive = true
+		challenges = append(challenges, challenge)
+	}
+	return challenges, rows.Err()
+}
+
+// MarkReleaseAnnounced marque la publication d'un challenge comme annoncée. Retourne
+// false si elle l'était déjà.
+func MarkReleaseAnnounced(challengeID int) (bool, error) {
+	result, err := DB.Exec("UPDATE challenges SET release_announced = 1 WHERE id = ? AND release_announced = 0", challengeID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+	"math"
+)
+
+var ErrInvalidScoring = errors.New("invalid dynamic scoring parameters")
+
+// DynamicValue calcule la valeur d'un challenge dynamique selon la formule de CTFd :
+// la valeur décroît de façon parabolique avec le nombre de résolutions, de initial
+// (premier solveur) jusqu'à minimum (atteint après decay résolutions).
+func DynamicValue(initial, minimum, decay, solveCount int) int {
+	if decay <= 0 {
+		return initial
+	}
+
+	// Le premier solveur obtient la valeur initiale
+	if solveCount > 0 {
+		solveCount--
+	}
+
+	value := (float64(minimum-initial)/float64(decay*decay))*float64(solveCount*solveCount) + float64(initial)
+	result := int(math.Ceil(value))
+	if result < minimum {
+		return minimum
+	}
+	return result
+}
+
+// recomputeChallengeValue recalcule la valeur d'un challenge dynamique d'après son nombre
+// de résolutions et répercute l'écart sur tous ses solveurs (joueurs, équipes et points
+// des soumissions) pour que le classement reste cohérent. Les soumissions de flags
+// partiels gardent les points de leur flag. Retourne la valeur courante.
+func recomputeChallengeValue(tx *sql.Tx, challengeID int) (int, error) {
+	var points, initial, minimum, decay, solves int
+	var scoringType string
+	err := tx.QueryRow(`SELECT points, IFNULL(scoring_type, 'static'), IFNULL(initial_value, 0),
+		IFNULL(minimum_value, 0), IFNULL(decay, 0),
+		(SELECT COUNT(*) FROM challenge_solves WHERE challenge_id = challenges.id)
+		FROM challenges WHERE id = ?`, challengeID).
+		Scan(&points, &scoringType, &initial, &minimum, &decay, &solves)
+	if err != nil {
+		return 0, err
+	}
+
+	value := points
+	switch scoringType {
+	case models.ScoringDynamic:
+		value = DynamicValue(initial, minimum, decay, solves)
+	case models.ScoringStatic:
+		if initial > 0 {
+			value = initial
+		}
+	}
+
+	delta := value - points
+	if delta == 0 {
+		return value, nil
+	}
+
+	statements := []string{
+		"UPDATE challenges SET points = ? WHERE id = ?",
+		"UPDATE users SET score = score + ? WHERE id IN (SELECT user_id FROM challenge_solves WHERE challenge_id = ?)",
+		"UPDATE teams SET score = score + ? WHERE id IN (SELECT team_id FROM challenge_solves WHERE challenge_id = ? AND team_id IS NOT NULL)",
+		`UPDATE submissions SET points_awarded = ? WHERE challenge_id = ? AND is_valid = 1 AND points_awarded > 0
+			AND (flag_id IS NULL OR flag_id NOT IN (SELECT id FROM flags WHERE points > 0))`,
+	}
+	args := [][]interface{}{
+		{value, challengeID},
+		{delta, challengeID},
+		{delta, challengeID},
+		{value, challengeID},
+	}
+	for i, statement := range statements {
+		if _, err := tx.Exec(statement, args[i]...); err != nil {
+			return 0, err
+		}
+	}
+
+	return value, nil
+}
+
+// UpdateChallengeScoring change le mode de calcul des points d'un challenge et
+// recalcule immédiatement sa valeur et les scores de ses solveurs
+func UpdateChallengeScoring(challengeID int, req *models.UpdateChallengeScoringRequest) (int, error) {
+	if req.ScoringType == models.ScoringDynamic && (req.Decay < 1 || req.MinimumValue < 1 || req.MinimumValue > req.InitialValue) {
+		return 0, ErrInvalidScoring
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE challenges SET scoring_type = ?, initial_value = ?, minimum_value = ?, decay = ?
+		WHERE id = ?`, req.ScoringType, req.InitialValue, req.MinimumValue, req.Decay, challengeID)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := recomputeChallengeValue(tx, challengeID)
+	if err != nil {
+		return 0, err
+	}
+
+	return value, tx.Commit()
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"backend/models"
+	"testing"
+)
+
+func TestDynamicValue(t *testing.T) {
+	tests := []struct {
+		solves int
+		want   int
+	}{
+		{0, 500},
+		{1, 500},
+		{2, 499},
+		{5, 484},
+		{11, 400},
+		{20, 139},
+		{21, 100},
+		{100, 100},
+	}
+
+	for _, tt := range tests {
+		if got := DynamicValue(500, 100, 20, tt.solves); got != tt.want {
+			t.Errorf("DynamicValue(500, 100, 20, %d) = %d, want %d", tt.solves, got, tt.want)
+		}
+	}
+}
+
+func TestDynamicScoringRecomputesEarlierSolvers(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	scoring := &models.UpdateChallengeScoringRequest{ScoringType: models.ScoringDynamic, InitialValue: 500, MinimumValue: 100, Decay: 2}
+	if _, err := UpdateChallengeScoring(1, scoring); err != nil {
+		t.Fatalf("UpdateChallengeScoring: %v", err)
+	}
+
+	for _, userID := range []int{alice, bob} {
+		submission := &models.Submission{UserID: userID, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}
+		if _, err := RecordSubmission(submission); err != nil {
+			t.Fatalf("RecordSubmission(user %d): %v", userID, err)
+		}
+	}
+
+	// Deux résolutions avec decay=2 : 500 + (100-500)/4 * 1 = 400 pour chacun
+	for _, userID := range []int{alice, bob} {
+		user, err := GetUserByID(userID)
+		if err != nil || user == nil {
+			t.Fatalf("GetUserByID(%d): %v", userID, err)
+		}
+		if user.Score != 400 {
+			t.Errorf("user %d score = %d, want 400", userID, user.Score)
+		}
+	}
+
+	challenge, err := GetChallengeByID(1)
+	if err != nil || challenge == nil {
+		t.Fatalf("GetChallengeByID: %v", err)
+	}
+	if challenge.Points != 400 {
+		t.Errorf("challenge value = %d, want 400", challenge.Points)
+	}
+}
+
+func TestUpdateChallengePointsAdjustsSolvers(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+
+	if _, err := RecordSubmission(&models.Submission{UserID: alice, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+
+	challenge, err := GetChallengeByID(1)
+	if err != nil || challenge == nil {
+		t.Fatalf("GetChallengeByID: %v", err)
+	}
+	challenge.Points = 200
+	challenge.IsActive = false
+	if err := UpdateChallenge(challenge); err != nil {
+		t.Fatalf("UpdateChallenge: %v", err)
+	}
+
+	if score := userScore(t, alice); score != 200 {
+		t.Errorf("solver score = %d, want 200", score)
+	}
+	if hidden, err := GetChallengeByID(1); err != nil || hidden != nil {
+		t.Errorf("deactivated challenge still listed as active (err: %v)", err)
+	}
+	if stored, err := GetChallengeIncludingInactive(1); err != nil || stored == nil || stored.Points != 200 {
+		t.Errorf("GetChallengeIncludingInactive = %+v, %v", stored, err)
+	}
+}
+
+func TestDynamicRecomputeKeepsPartialFlagPoints(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	scoring := &models.UpdateChallengeScoringRequest{ScoringType: models.ScoringDynamic, InitialValue: 500, MinimumValue: 100, Decay: 2}
+	if _, err := UpdateChallengeScoring(1, scoring); err != nil {
+		t.Fatalf("UpdateChallengeScoring: %v", err)
+	}
+	part := &models.Flag{ChallengeID: 1, Type: models.FlagStatic, Content: "CTF{part_one}", CaseSensitive: true, Points: 25}
+	if err := CreateFlag(part); err != nil {
+		t.Fatalf("CreateFlag: %v", err)
+	}
+
+	partial := &models.Submission{UserID: alice, ChallengeID: 1, FlagID: &part.ID, SubmittedFlag: part.Content, IsValid: true}
+	if _, err := RecordSubmission(partial); err != nil {
+		t.Fatalf("RecordSubmission(partial): %v", err)
+	}
+	for _, userID := range []int{alice, bob} {
+		submission := &models.Submission{UserID: userID, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}
+		if _, err := RecordSubmission(submission); err != nil {
+			t.Fatalf("RecordSubmission(user %d): %v", userID, err)
+		}
+	}
+
+	pointsAwarded := func() int {
+		t.Helper()
+		var points int
+		if err := DB.QueryRow("SELECT points_awarded FROM submissions WHERE id = ?", partial.ID).Scan(&points); err != nil {
+			t.Fatalf("read partial submission: %v", err)
+		}
+		return points
+	}
+	if points := pointsAwarded(); points != 25 {
+		t.Errorf("partial submission points after recompute = %d, want 25", points)
+	}
+
+	// Un flag partiel supprimé ne rapporte plus rien, même après un nouveau recalcul
+	if err := DeleteFlag(part.ID); err != nil {
+		t.Fatalf("DeleteFlag: %v", err)
+	}
+	carol := createTestUser(t, "carol")
+	if _, err := RecordSubmission(&models.Submission{UserID: carol, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}); err != nil {
+		t.Fatalf("RecordSubmission(carol): %v", err)
+	}
+	if points := pointsAwarded(); points != 0 {
+		t.Errorf("deleted partial flag submission points = %d, want 0", points)
+	}
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Clés de la table settings
+const (
+	settingMode           = "mode"
+	settingShareInstances = "share_instances"
+	settingBloodBonuses   = "blood_bonuses"
+	settingLocked         = "locked_challenges"
+)
+
+// getSetting lit un réglage, ou retourne defaultValue s'il n'a jamais été défini
+func getSetting(key, defaultValue string) (string, error) {
+	var value string
+	err := DB.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return defaultValue, nil
+		}
+		return "", err
+	}
+	return value, nil
+}
+
+// GetEventSettings récupère les réglages de la plateforme (mode individuel par
+// défaut). Les événements hébergés peuvent remplacer le mode de jeu et le partage
+// d'instances : voir GetScopedSettings.
+func GetEventSettings() (*models.EventSettings, error) {
+	mode, err := getSetting(settingMode, models.ModeIndividual)
+	if err != nil {
+		return nil, err
+	}
+
+	share, err := getSetting(settingShareInstances, "false")
+	if err != nil {
+		return nil, err
+	}
+	shareInstances, _ := strconv.ParseBool(share)
+
+	bonuses, err := getSetting(settingBloodBonuses, "")
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := getSetting(settingLocked, models.LockedShown)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EventSettings{
+		Mode:             mode,
+		ShareInstances:   shareInstances,
+		BloodBonuses:     parseBloodBonuses(bonuses),
+		LockedChallenges: locked,
+	}, nil
+}
+
+// GetScopedSettings récupère les réglages qui s'appliquent aux challenges de
+// l'événement hébergé eventID : ceux de la plateforme (eventID 0), dont le mode de jeu
+// et le partage d'instances sont remplacés par ceux de l'événement s'il en définit
+func GetScopedSettings(eventID int) (*models.EventSettings, error) {
+	settings, err := GetEventSettings()
+	if err != nil || eventID == 0 {
+		return settings, err
+	}
+
+	var mode sql.NullString
+	var shareInstances sql.NullBool
+	err = DB.QueryRow("SELECT mode, share_instances FROM events WHERE id = ?", eventID).Scan(&mode, &shareInstances)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return settings, nil
+		}
+		return nil, err
+	}
+	if mode.Valid && mode.String != "" {
+		settings.Mode = mode.String
+	}
+	if shareInstances.Valid {
+		settings.ShareInstances = shareInstances.Bool
+	}
+	return settings, nil
+}
+
+// parseBloodBonuses lit une liste de bonus séparés par des virgules (ex: "50,30,10")
+func parseBloodBonuses(value string) []int {
+	bonuses := []int{}
+	if value == "" {
+		return bonuses
+	}
+	for _, part := range strings.Split(value, ",") {
+		bonus, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || bonus < 0 {
+			bonus = 0
+		}
+		bonuses = append(bonuses, bonus)
+	}
+	return bonuses
+}
+
+func formatBloodBonuses(bonuses []int) string {
+	parts := make([]string, len(bonuses))
+	for i, bonus := range bonuses {
+		parts[i] = strconv.Itoa(bonus)
+	}
+	return strings.Join(parts, ",")
+}
+
+// UpdateEventSettings enregistre les réglages de l'événement
+func UpdateEventSettings(settings *models.EventSettings) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	values := map[string]string{
+		settingMode:           settings.Mode,
+		settingShareInstances: strconv.FormatBool(settings.ShareInstances),
+		settingBloodBonuses:   formatBloodBonuses(settings.BloodBonuses),
+		settingLocked:         settings.LockedChallenges,
+	}
+	for key, value := range values {
+		_, err := tx.Exec(`INSERT INTO settings (key, value) VALUES (?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
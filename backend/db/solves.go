@@ -0,0 +1,71 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// GetChallengeSolves récupère les résolutions d'un challenge dans l'ordre, antérieures
+// à frozenAt s'il est défini
+func GetChallengeSolves(challengeID int, frozenAt *time.Time) ([]models.ChallengeSolve, error) {
+	return getSolves("cs.challenge_id = ? AND cs.solved_at < ?", challengeID, scoreboardUntil(frozenAt))
+}
+
+// GetUserSolves récupère les résolutions d'un utilisateur avec leur rang sur chaque
+// challenge, antérieures à frozenAt s'il est défini
+func GetUserSolves(userID int, frozenAt *time.Time) ([]models.ChallengeSolve, error) {
+	return getSolves("cs.user_id = ? AND cs.solved_at < ?", userID, scoreboardUntil(frozenAt))
+}
+
+// GetAllSolves récupère l'historique complet des résolutions, de la plus ancienne à
+// la plus récente
+func GetAllSolves() ([]models.ChallengeSolve, error) {
+	return getSolves("1 = 1")
+}
+
+// GetEventSolves récupère l'historique des résolutions des challenges d'un événement
+// hébergé, de la plus ancienne à la plus récente
+func GetEventSolves(eventID int) ([]models.ChallengeSolve, error) {
+	return getSolves("c.event_id = ?", eventID)
+}
+
+func getSolves(filter string, args ...interface{}) ([]models.ChallengeSolve, error) {
+	rows, err := DB.Query(`SELECT cs.id, cs.user_id, u.username, cs.team_id, IFNULL(t.name, ''),
+		cs.challenge_id, c.name, c.event_id, IFNULL(e.slug, ''), IFNULL(cs.solve_rank, 0), IFNULL(cs.bonus, 0), cs.solved_at
+		FROM challenge_solves cs
+		JOIN users u ON cs.user_id = u.id
+		JOIN challenges c ON cs.challenge_id = c.id
+		LEFT JOIN teams t ON cs.team_id = t.id
+		LEFT JOIN events e ON c.event_id = e.id
+		WHERE `+filter+`
+		ORDER BY cs.solved_at ASC, cs.id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	solves := []models.ChallengeSolve{}
+	for rows.Next() {
+		var solve models.ChallengeSolve
+		var teamID, eventID sql.NullInt64
+		var solvedAtStr string
+
+		err := rows.Scan(&solve.ID, &solve.UserID, &solve.Username, &teamID, &solve.TeamName,
+			&solve.ChallengeID, &solve.ChallengeName, &eventID, &solve.EventSlug, &solve.SolveRank, &solve.Bonus, &solvedAtStr)
+		if err != nil {
+			log.Printf("Error scanning solve: %v", err)
+			continue
+		}
+
+		solve.TeamID = nullableInt(teamID)
+		solve.EventID = nullableInt(eventID)
+		if solve.SolvedAt, err = parseTimestamp(solvedAtStr); err != nil {
+			log.Printf("Warning: failed to parse solved_at date: %v", err)
+		}
+		solves = append(solves, solve)
+	}
+
+	return solves, rows.Err()
+}
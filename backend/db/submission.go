@@ -0,0 +1,279 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// RecordSubmission enregistre une soumission de flag. Si le flag est valide et
+// qu'il s'agit de la première résolution du challenge par l'utilisateur (ou par
+// son équipe si submission.TeamID est défini), la résolution est ajoutée et les
+// points crédités dans la même transaction. Pour un challenge dynamique, sa valeur
+// est ensuite recalculée pour tous les solveurs. Les premières résolutions reçoivent
+// en plus le bonus de rang configuré. Un flag partiel (submission.FlagID) crédite
+// seulement ses points, une fois par joueur ou par équipe. Retourne true si des
+// points ont été attribués.
+func RecordSubmission(submission *models.Submission) (bool, error) {
+	settings, err := GetEventSettings()
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var points int
+	var scoringType string
+	err = tx.QueryRow("SELECT points, IFNULL(scoring_type, 'static') FROM challenges WHERE id = ?", submission.ChallengeID).
+		Scan(&points, &scoringType)
+	if err != nil {
+		return false, err
+	}
+
+	partialPoints := 0
+	if submission.IsValid && submission.FlagID != nil {
+		if err := tx.QueryRow("SELECT points FROM flags WHERE id = ?", *submission.FlagID).Scan(&partialPoints); err != nil {
+			return false, err
+		}
+	}
+
+	awarded := false
+	if submission.IsValid && partialPoints > 0 {
+		if awarded, err = recordFlagCapture(tx, submission, partialPoints); err != nil {
+			return false, err
+		}
+	} else if submission.IsValid {
+		result, err := tx.Exec(`INSERT OR IGNORE INTO challenge_solves (user_id, challenge_id, team_id) VALUES (?, ?, ?)`,
+			submission.UserID, submission.ChallengeID, submission.TeamID)
+		if err != nil {
+			return false, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+
+		if affected == 1 {
+			solveID, err := result.LastInsertId()
+			if err != nil {
+				return false, err
+			}
+
+			// Le rang est le nombre de résolutions du challenge, celle-ci comprise
+			var rank int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM challenge_solves WHERE challenge_id = ?", submission.ChallengeID).Scan(&rank); err != nil {
+				return false, err
+			}
+			bonus := 0
+			if rank <= len(settings.BloodBonuses) {
+				bonus = settings.BloodBonuses[rank-1]
+			}
+			if _, err := tx.Exec("UPDATE challenge_solves SET solve_rank = ?, bonus = ? WHERE id = ?", rank, bonus, solveID); err != nil {
+				return false, err
+			}
+
+			if _, err := tx.Exec("UPDATE users SET score = score + ? WHERE id = ?", points+bonus, submission.UserID); err != nil {
+				return false, err
+			}
+			if submission.TeamID != nil {
+				if _, err := tx.Exec("UPDATE teams SET score = score + ? WHERE id = ?", points+bonus, *submission.TeamID); err != nil {
+					return false, err
+				}
+			}
+			submission.PointsAwarded = points
+			submission.SolveRank = rank
+			submission.Bonus = bonus
+			awarded = true
+		} else if submission.TeamID != nil {
+			if awarded, err = creditTeamSolve(tx, submission, points); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	result, err := tx.Exec(`INSERT INTO submissions (user_id, challenge_id, instance_id, team_id, submitted_flag, is_valid, points_awarded, flag_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		submission.UserID, submission.ChallengeID, submission.InstanceID, submission.TeamID, submission.SubmittedFlag,
+		submission.IsValid, submission.PointsAwarded, submission.FlagID)
+	if err != nil {
+		return false, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	if awarded && partialPoints == 0 && scoringType == models.ScoringDynamic {
+		value, err := recomputeChallengeValue(tx, submission.ChallengeID)
+		if err != nil {
+			return false, err
+		}
+		submission.PointsAwarded = value
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	submission.ID = int(id)
+	return awarded, nil
+}
+
+// creditTeamSolve reporte sur l'équipe du joueur la résolution qu'il a faite avant de
+// la rejoindre, si l'équipe n'a pas encore résolu le challenge. Le joueur garde ses
+// points ; l'équipe reçoit la valeur courante et le bonus de cette résolution. Son
+// rang ne change pas (SolveRank reste à 0).
+func creditTeamSolve(tx *sql.Tx, submission *models.Submission, points int) (bool, error) {
+	result, err := tx.Exec(`UPDATE challenge_solves SET team_id = ?
+		WHERE user_id = ? AND challenge_id = ? AND team_id IS NULL
+		AND NOT EXISTS (SELECT 1 FROM challenge_solves WHERE team_id = ? AND challenge_id = ?)`,
+		*submission.TeamID, submission.UserID, submission.ChallengeID, *submission.TeamID, submission.ChallengeID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	var bonus int
+	err = tx.QueryRow("SELECT IFNULL(bonus, 0) FROM challenge_solves WHERE user_id = ? AND challenge_id = ?",
+		submission.UserID, submission.ChallengeID).Scan(&bonus)
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("UPDATE teams SET score = score + ? WHERE id = ?", points+bonus, *submission.TeamID); err != nil {
+		return false, err
+	}
+
+	submission.PointsAwarded = points
+	submission.Bonus = bonus
+	return true, nil
+}
+
+// recordFlagCapture crédite les points d'un flag partiel au joueur et à son équipe
+// s'ils ne l'ont pas encore validé
+func recordFlagCapture(tx *sql.Tx, submission *models.Submission, points int) (bool, error) {
+	result, err := tx.Exec(`INSERT OR IGNORE INTO flag_captures (flag_id, user_id, team_id, points) VALUES (?, ?, ?, ?)`,
+		*submission.FlagID, submission.UserID, submission.TeamID, points)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET score = score + ? WHERE id = ?", points, submission.UserID); err != nil {
+		return false, err
+	}
+	if submission.TeamID != nil {
+		if _, err := tx.Exec("UPDATE teams SET score = score + ? WHERE id = ?", points, *submission.TeamID); err != nil {
+			return false, err
+		}
+	}
+	submission.PointsAwarded = points
+	return true, nil
+}
+
+// GetUserInstanceFlags récupère les flags des instances d'un utilisateur pour un challenge, indexés par ID d'instance
+func GetUserInstanceFlags(userID, challengeID int) (map[int]string, error) {
+	return getInstanceFlags("user_id = ?", userID, challengeID)
+}
+
+// getInstanceFlags récupère les flags des instances d'un challenge filtrées par propriétaire
+func getInstanceFlags(ownerFilter string, ownerID, challengeID int) (map[int]string, error) {
+	rows, err := DB.Query(`SELECT id, flag FROM instances
+		WHERE `+ownerFilter+` AND challenge_id = ? AND flag IS NOT NULL AND flag != ''`, ownerID, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var flag string
+		if err := rows.Scan(&id, &flag); err != nil {
+			return nil, err
+		}
+		flags[id] = flag
+	}
+
+	return flags, rows.Err()
+}
+
+// GetTeamInstanceFlags récupère les flags des instances des membres d'une équipe pour un challenge, indexés par ID d'instance
+func GetTeamInstanceFlags(teamID, challengeID int) (map[int]string, error) {
+	return getInstanceFlags("user_id IN (SELECT id FROM users WHERE team_id = ?)", teamID, challengeID)
+}
+
+// FindInstanceByFlag recherche l'instance d'un autre utilisateur à laquelle appartient un flag
+func FindInstanceByFlag(challengeID int, flag string, excludeUserID int) (*models.Instance, error) {
+	var instance models.Instance
+	err := DB.QueryRow(`SELECT id, user_id, challenge_id FROM instances
+		WHERE challenge_id = ? AND flag = ? AND user_id != ?`, challengeID, flag, excludeUserID).
+		Scan(&instance.ID, &instance.UserID, &instance.ChallengeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// CreateFlagReview signale une soumission utilisant le flag de l'instance d'un autre joueur
+func CreateFlagReview(review *models.FlagReview) error {
+	result, err := DB.Exec(`INSERT INTO flag_reviews (submission_id, user_id, challenge_id, instance_id, owner_id)
+		VALUES (?, ?, ?, ?, ?)`,
+		review.SubmissionID, review.UserID, review.ChallengeID, review.InstanceID, review.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	review.ID = int(id)
+	return nil
+}
+
+// GetFlagReviews récupère les soumissions signalées pour partage de flag
+func GetFlagReviews() ([]models.FlagReview, error) {
+	rows, err := DB.Query(`SELECT r.id, r.submission_id, r.user_id, u.username, r.challenge_id,
+		r.instance_id, r.owner_id, o.username, r.created_at
+		FROM flag_reviews r
+		JOIN users u ON r.user_id = u.id
+		JOIN users o ON r.owner_id = o.id
+		ORDER BY r.created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []models.FlagReview{}
+	for rows.Next() {
+		var review models.FlagReview
+		err := rows.Scan(&review.ID, &review.SubmissionID, &review.UserID, &review.Username,
+			&review.ChallengeID, &review.InstanceID, &review.OwnerID, &review.OwnerName, &review.CreatedAt)
+		if err != nil {
+			log.Printf("Error scanning flag review: %v", err)
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
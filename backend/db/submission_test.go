@@ -0,0 +1,42 @@
+package db
+
+import (
+	"backend/models"
+	"testing"
+)
+
+func TestBloodBonusesFollowSolveOrder(t *testing.T) {
+	setupTestDB(t)
+	settings := &models.EventSettings{Mode: models.ModeIndividual, BloodBonuses: []int{50, 20}}
+	if err := UpdateEventSettings(settings); err != nil {
+		t.Fatalf("UpdateEventSettings: %v", err)
+	}
+
+	wantBonus := []int{50, 20, 0}
+	for i, name := range []string{"alice", "bob", "carol"} {
+		userID := createTestUser(t, name)
+		submission := &models.Submission{UserID: userID, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}
+		if _, err := RecordSubmission(submission); err != nil {
+			t.Fatalf("RecordSubmission(%s): %v", name, err)
+		}
+		if submission.SolveRank != i+1 || submission.Bonus != wantBonus[i] {
+			t.Errorf("%s: rank %d bonus %d, want rank %d bonus %d", name, submission.SolveRank, submission.Bonus, i+1, wantBonus[i])
+		}
+
+		user, err := GetUserByID(userID)
+		if err != nil || user == nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if want := 75 + wantBonus[i]; user.Score != want {
+			t.Errorf("%s score = %d, want %d", name, user.Score, want)
+		}
+	}
+
+	solves, err := GetChallengeSolves(1, nil)
+	if err != nil {
+		t.Fatalf("GetChallengeSolves: %v", err)
+	}
+	if len(solves) != 3 || solves[0].Username != "alice" || solves[0].SolveRank != 1 {
+		t.Errorf("unexpected solve order: %+v", solves)
+	}
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// CreateSuspiciousActivity consigne une activité suspecte
+func CreateSuspiciousActivity(activity *models.SuspiciousActivity) error {
+	result, err := DB.Exec(`INSERT INTO suspicious_activities (type, user_id, challenge_id, ip, details)
+		VALUES (?, ?, ?, ?, ?)`,
+		activity.Type, activity.UserID, activity.ChallengeID, nullableString(activity.IP), activity.Details)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	activity.ID = int(id)
+	return nil
+}
+
+// GetSuspiciousActivities récupère les activités suspectes les plus récentes
+func GetSuspiciousActivities(limit int) ([]models.SuspiciousActivity, error) {
+	rows, err := DB.Query(`SELECT a.id, a.type, a.user_id, IFNULL(u.username, ''), a.challenge_id,
+		IFNULL(a.ip, ''), a.details, a.created_at
+		FROM suspicious_activities a
+		LEFT JOIN users u ON a.user_id = u.id
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activities := []models.SuspiciousActivity{}
+	for rows.Next() {
+		var activity models.SuspiciousActivity
+		var userID, challengeID sql.NullInt64
+		var createdAtStr string
+
+		err := rows.Scan(&activity.ID, &activity.Type, &userID, &activity.Username, &challengeID,
+			&activity.IP, &activity.Details, &createdAtStr)
+		if err != nil {
+			log.Printf("Error scanning suspicious activity: %v", err)
+			continue
+		}
+
+		activity.UserID = nullableInt(userID)
+		activity.ChallengeID = nullableInt(challengeID)
+		if activity.CreatedAt, err = parseTimestamp(createdAtStr); err != nil {
+			log.Printf("Warning: failed to parse created_at date: %v", err)
+		}
+		activities = append(activities, activity)
+	}
+
+	return activities, rows.Err()
+}
+
+// GetRecentWrongSubmissionTimes récupère, de la plus récente à la plus ancienne, les
+// dates des soumissions incorrectes d'un utilisateur sur un challenge depuis since
+func GetRecentWrongSubmissionTimes(userID, challengeID int, since time.Time, limit int) ([]time.Time, error) {
+	rows, err := DB.Query(`SELECT submitted_at FROM submissions
+		WHERE user_id = ? AND challenge_id = ? AND is_valid = 0 AND submitted_at >= ?
+		ORDER BY submitted_at DESC, id DESC
+		LIMIT ?`, userID, challengeID, formatTimestamp(since), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	times := []time.Time{}
+	for rows.Next() {
+		var submittedAtStr string
+		if err := rows.Scan(&submittedAtStr); err != nil {
+			return nil, err
+		}
+		submittedAt, err := parseTimestamp(submittedAtStr)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, submittedAt)
+	}
+
+	return times, rows.Err()
+}
+
+// CountUsersWithWrongFlag compte les utilisateurs ayant soumis un même flag incorrect sur un challenge
+func CountUsersWithWrongFlag(challengeID int, flag string) (int, error) {
+	var count int
+	err := DB.QueryRow(`SELECT COUNT(DISTINCT user_id) FROM submissions
+		WHERE challenge_id = ? AND submitted_flag = ? AND is_valid = 0`, challengeID, flag).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	ErrAlreadyInTeam = errors.New("user already belongs to a team")
+	ErrNotInTeam     = errors.New("user does not belong to this team")
+	ErrTeamNameTaken = errors.New("team name already taken")
+)
+
+// CreateTeam crée une équipe dont team.CaptainID devient capitaine et premier membre
+func CreateTeam(team *models.Team) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO teams (name, invite_code, captain_id) VALUES (?, ?, ?)",
+		team.Name, team.InviteCode, team.CaptainID)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return ErrTeamNameTaken
+		}
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := joinTeam(tx, team.CaptainID, int(id)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	team.ID = int(id)
+	return nil
+}
+
+// GetTeamByID récupère une équipe par son ID
+func GetTeamByID(id int) (*models.Team, error) {
+	return getTeam("id = ?", id)
+}
+
+// GetTeamByInviteCode récupère une équipe par son code d'invitation
+func GetTeamByInviteCode(code string) (*models.Team, error) {
+	return getTeam("invite_code = ?", code)
+}
+
+func getTeam(filter string, arg interface{}) (*models.Team, error) {
+	var team models.Team
+	var captainID sql.NullInt64
+	var createdAtStr string
+
+	err := DB.QueryRow("SELECT id, name, invite_code, captain_id, score, created_at FROM teams WHERE "+filter, arg).
+		Scan(&team.ID, &team.Name, &team.InviteCode, &captainID, &team.Score, &createdAtStr)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	team.CaptainID = int(captainID.Int64)
+	if team.CreatedAt, err = parseTimestamp(createdAtStr); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// GetTeamMembers récupère les membres d'une équipe avec les points rapportés par chacun
+func GetTeamMembers(teamID int) ([]models.TeamMember, error) {
+	rows, err := DB.Query(`SELECT u.id, u.username,
+		IFNULL((SELECT SUM(c.points + IFNULL(cs.bonus, 0)) FROM challenge_solves cs
+			JOIN challenges c ON cs.challenge_id = c.id
+			WHERE cs.user_id = u.id AND cs.team_id = u.team_id), 0)
+		+ IFNULL((SELECT SUM(fc.points) FROM flag_captures fc
+			WHERE fc.user_id = u.id AND fc.team_id = u.team_id), 0),
+		u.id = IFNULL(t.captain_id, 0)
+		FROM users u
+		JOIN teams t ON u.team_id = t.id
+		WHERE u.team_id = ?
+		ORDER BY u.id ASC`, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.TeamMember{}
+	for rows.Next() {
+		var member models.TeamMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Score, &member.IsCaptain); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// GetUserTeamID retourne l'ID de l'équipe d'un utilisateur, ou 0 s'il n'en a pas
+func GetUserTeamID(userID int) (int, error) {
+	var teamID sql.NullInt64
+	err := DB.QueryRow("SELECT team_id FROM users WHERE id = ?", userID).Scan(&teamID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	return int(teamID.Int64), nil
+}
+
+// JoinTeam ajoute un utilisateur sans équipe à une équipe. Une équipe
+// sans capitaine (tous ses membres sont partis) est reprise par ce dernier.
+func JoinTeam(userID, teamID int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := joinTeam(tx, userID, teamID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE teams SET captain_id = ? WHERE id = ? AND captain_id IS NULL", userID, teamID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func joinTeam(tx *sql.Tx, userID, teamID int) error {
+	result, err := tx.Exec("UPDATE users SET team_id = ? WHERE id = ? AND team_id IS NULL", teamID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAlreadyInTeam
+	}
+	return nil
+}
+
+// RemoveTeamMember retire un utilisateur de son équipe. Si c'était le capitaine,
+// le membre le plus ancien lui succède. Une équipe vide sans aucune résolution
+// est supprimée ; sinon elle est conservée pour le classement.
+func RemoveTeamMember(teamID, userID int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE users SET team_id = NULL WHERE id = ? AND team_id = ?", userID, teamID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotInTeam
+	}
+
+	_, err = tx.Exec(`UPDATE teams SET captain_id = (SELECT MIN(id) FROM users WHERE team_id = ?)
+		WHERE id = ? AND captain_id = ?`, teamID, teamID, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM teams WHERE id = ?
+		AND NOT EXISTS (SELECT 1 FROM users WHERE team_id = ?)
+		AND NOT EXISTS (SELECT 1 FROM challenge_solves WHERE team_id = ?)`, teamID, teamID, teamID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// TransferTeamCaptain désigne un autre membre de l'équipe comme capitaine
+func TransferTeamCaptain(teamID, userID int) error {
+	result, err := DB.Exec(`UPDATE teams SET captain_id = ?
+		WHERE id = ? AND EXISTS (SELECT 1 FROM users WHERE id = ? AND team_id = ?)`,
+		userID, teamID, userID, teamID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotInTeam
+	}
+	return nil
+}
+
+// UpdateTeamInviteCode remplace le code d'invitation d'une équipe
+func UpdateTeamInviteCode(teamID int, code string) error {
+	_, err := DB.Exec("UPDATE teams SET invite_code = ? WHERE id = ?", code, teamID)
+	return err
+}
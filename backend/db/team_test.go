@@ -0,0 +1,137 @@
+package db
+
+import (
+	"backend/models"
+	"path/filepath"
+	"testing"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "ctf.sqlite"))
+	InitDB()
+	t.Cleanup(CloseDB)
+}
+
+func createTestUser(t *testing.T, username string) int {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", PasswordHash: "x", Role: "user"}
+	if err := InsertUser(user); err != nil {
+		t.Fatalf("InsertUser(%s): %v", username, err)
+	}
+	return user.ID
+}
+
+func TestTeamSolveIsCreditedOnce(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	team := &models.Team{Name: "pwners", InviteCode: "code", CaptainID: alice}
+	if err := CreateTeam(team); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if err := JoinTeam(bob, team.ID); err != nil {
+		t.Fatalf("JoinTeam: %v", err)
+	}
+
+	for _, userID := range []int{alice, bob} {
+		submission := &models.Submission{UserID: userID, ChallengeID: 1, TeamID: &team.ID, SubmittedFlag: "flag", IsValid: true}
+		awarded, err := RecordSubmission(submission)
+		if err != nil {
+			t.Fatalf("RecordSubmission(user %d): %v", userID, err)
+		}
+		if want := userID == alice; awarded != want {
+			t.Errorf("RecordSubmission(user %d) awarded = %t, want %t", userID, awarded, want)
+		}
+	}
+
+	updated, err := GetTeamByID(team.ID)
+	if err != nil || updated == nil {
+		t.Fatalf("GetTeamByID: %v", err)
+	}
+	if updated.Score != 75 {
+		t.Errorf("team score = %d, want 75", updated.Score)
+	}
+}
+
+func TestPreTeamSolveIsCreditedToTeam(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	// Alice résout le challenge seule, puis fonde une équipe que Bob rejoint
+	if _, err := RecordSubmission(&models.Submission{UserID: alice, ChallengeID: 1, SubmittedFlag: "flag", IsValid: true}); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+	team := &models.Team{Name: "pwners", InviteCode: "code", CaptainID: alice}
+	if err := CreateTeam(team); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if err := JoinTeam(bob, team.ID); err != nil {
+		t.Fatalf("JoinTeam: %v", err)
+	}
+
+	// Sa nouvelle soumission crédite l'équipe, une seule fois
+	for _, userID := range []int{alice, alice, bob} {
+		submission := &models.Submission{UserID: userID, ChallengeID: 1, TeamID: &team.ID, SubmittedFlag: "flag", IsValid: true}
+		awarded, err := RecordSubmission(submission)
+		if err != nil {
+			t.Fatalf("RecordSubmission(user %d): %v", userID, err)
+		}
+		if awarded != (userID == alice && submission.ID == 2) {
+			t.Errorf("submission %d by user %d awarded = %t", submission.ID, userID, awarded)
+		}
+	}
+
+	updated, err := GetTeamByID(team.ID)
+	if err != nil || updated == nil {
+		t.Fatalf("GetTeamByID: %v", err)
+	}
+	if updated.Score != 75 {
+		t.Errorf("team score = %d, want 75", updated.Score)
+	}
+	user, err := GetUserByID(alice)
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if user.Score != 75 {
+		t.Errorf("alice score = %d, want 75 (not credited twice)", user.Score)
+	}
+}
+
+func TestCaptainLeavingPromotesNextMember(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	team := &models.Team{Name: "pwners", InviteCode: "code", CaptainID: alice}
+	if err := CreateTeam(team); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if err := JoinTeam(bob, team.ID); err != nil {
+		t.Fatalf("JoinTeam: %v", err)
+	}
+	if err := JoinTeam(bob, team.ID); err != ErrAlreadyInTeam {
+		t.Errorf("second JoinTeam error = %v, want ErrAlreadyInTeam", err)
+	}
+
+	if err := RemoveTeamMember(team.ID, alice); err != nil {
+		t.Fatalf("RemoveTeamMember(alice): %v", err)
+	}
+	updated, err := GetTeamByID(team.ID)
+	if err != nil || updated == nil {
+		t.Fatalf("GetTeamByID: %v", err)
+	}
+	if updated.CaptainID != bob {
+		t.Errorf("captain = %d, want %d", updated.CaptainID, bob)
+	}
+
+	// Le dernier membre part et l'équipe n'a rien résolu : elle disparaît
+	if err := RemoveTeamMember(team.ID, bob); err != nil {
+		t.Fatalf("RemoveTeamMember(bob): %v", err)
+	}
+	if deleted, err := GetTeamByID(team.ID); err != nil || deleted != nil {
+		t.Errorf("empty team still exists (err: %v)", err)
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"backend/db"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAnnouncements retourne les dernières annonces (first bloods, nouveaux challenges...)
+func GetAnnouncements(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le paramètre limit doit être entre 1 et 100"})
+		return
+	}
+
+	announcements, err := db.GetAnnouncements(limit)
+	if err != nil {
+		log.Printf("Error fetching announcements: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des annonces"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"announcements": announcements})
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attachmentStorage retourne le stockage des pièces jointes, ou envoie une erreur s'il
+// n'a pas pu être initialisé au démarrage
+func attachmentStorage(c *gin.Context) services.FileStorage {
+	if services.Storage == nil {
+		log.Printf("Attachment storage not initialized")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'initialisation du stockage"})
+		return nil
+	}
+	return services.Storage
+}
+
+// UploadAttachment ajoute un fichier (champ multipart "file") à un challenge (admin seulement)
+func UploadAttachment(c *gin.Context) {
+	challengeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	challenge, err := db.GetChallengeByID(challengeID)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", challengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	storage := attachmentStorage(c)
+	if storage == nil {
+		return
+	}
+
+	maxSize := services.AttachmentMaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Fichier trop volumineux (maximum %d Mo)", maxSize>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fichier manquant (champ multipart \"file\")", "details": err.Error()})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la lecture du fichier"})
+		return
+	}
+	defer file.Close()
+
+	key, err := services.AttachmentKey(challengeID, header.Filename)
+	if err != nil {
+		log.Printf("Error generating attachment key: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement du fichier"})
+		return
+	}
+
+	size, checksum, err := storage.Save(key, file)
+	if err != nil {
+		log.Printf("Error storing attachment %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement du fichier"})
+		return
+	}
+
+	attachment := &models.Attachment{
+		ChallengeID: challengeID,
+		Filename:    header.Filename,
+		StorageKey:  key,
+		Size:        size,
+		SHA256:      checksum,
+	}
+	if err := db.CreateAttachment(attachment); err != nil {
+		log.Printf("Error saving attachment to database: %v", err)
+		if deleteErr := storage.Delete(key); deleteErr != nil {
+			log.Printf("Failed to delete stored file %s: %v", key, deleteErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement du fichier"})
+		return
+	}
+
+	log.Printf("Attachment %q (%d bytes, sha256 %s) added to challenge %d", attachment.Filename, size, checksum, challengeID)
+	c.JSON(http.StatusCreated, gin.H{"attachment": attachment})
+}
+
+// DeleteAttachment supprime une pièce jointe et son fichier (admin seulement)
+func DeleteAttachment(c *gin.Context) {
+	attachment := loadAttachment(c)
+	if attachment == nil {
+		return
+	}
+
+	storage := attachmentStorage(c)
+	if storage == nil {
+		return
+	}
+
+	if err := db.DeleteAttachment(attachment.ID); err != nil {
+		log.Printf("Error deleting attachment %d: %v", attachment.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression du fichier"})
+		return
+	}
+	if err := storage.Delete(attachment.StorageKey); err != nil {
+		log.Printf("Failed to delete stored file %s: %v", attachment.StorageKey, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Fichier supprimé avec succès"})
+}
+
+// GetAttachmentLink retourne une URL de téléchargement signée et temporaire pour
+// l'utilisateur connecté
+func GetAttachmentLink(c *gin.Context) {
+	attachment := loadAttachment(c)
+	if attachment == nil {
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	// Les fichiers d'un challenge désactivé, non publié ou verrouillé ne sont pas distribués
+	challenge, err := db.GetChallengeByID(attachment.ChallengeID)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", attachment.ChallengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	if _, ok := requireChallengeAccessible(c, challenge, userID.(int)); !ok {
+		return
+	}
+
+	url, expiresAt, err := services.SignAttachmentURL(attachment.ID, userID.(int))
+	if err != nil {
+		log.Printf("Error signing attachment %d URL: %v", attachment.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la génération du lien de téléchargement"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"url":        url,
+		"expires_at": expiresAt,
+		"filename":   attachment.Filename,
+		"sha256":     attachment.SHA256,
+	})
+}
+
+// DownloadAttachment sert une pièce jointe à partir d'une URL signée non expirée
+func DownloadAttachment(c *gin.Context) {
+	attachment := loadAttachment(c)
+	if attachment == nil {
+		return
+	}
+
+	userID, _ := strconv.Atoi(c.Query("uid"))
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if !services.VerifyAttachmentSignature(attachment.ID, userID, expires, c.Query("sig")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Lien de téléchargement invalide ou expiré"})
+		return
+	}
+
+	storage := attachmentStorage(c)
+	if storage == nil {
+		return
+	}
+
+	file, err := storage.Open(attachment.StorageKey)
+	if err != nil {
+		log.Printf("Error opening stored file %s: %v", attachment.StorageKey, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la lecture du fichier"})
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, "application/octet-stream", file, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", attachment.Filename),
+		"X-Checksum-SHA256":   attachment.SHA256,
+	})
+}
+
+// loadAttachment récupère la pièce jointe désignée par le paramètre :id. En cas
+// d'échec, la réponse d'erreur est déjà envoyée et nil est retourné.
+func loadAttachment(c *gin.Context) *models.Attachment {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil
+	}
+
+	attachment, err := db.GetAttachmentByID(id)
+	if err != nil {
+		log.Printf("Error fetching attachment %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du fichier"})
+		return nil
+	}
+	if attachment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fichier non trouvé"})
+		return nil
+	}
+	return attachment
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FlagRateLimit limite les soumissions de flags par utilisateur (FLAG_RATE_USER, par
+// défaut 10/1m) et par adresse IP (FLAG_RATE_IP, par défaut 30/1m). Le premier refus
+// de chaque rafale est consigné dans le journal des activités suspectes. Doit être
+// utilisé après AuthMiddleware.
+func FlagRateLimit() gin.HandlerFunc {
+	userLimiter := services.RateLimiterFromEnv("FLAG_RATE_USER", "10/1m")
+	ipLimiter := services.RateLimiterFromEnv("FLAG_RATE_IP", "30/1m")
+
+	return func(c *gin.Context) {
+		userID := c.GetInt("user_id")
+		ip := c.ClientIP()
+
+		checks := []struct {
+			limiter *services.RateLimiter
+			key     string
+			scope   string
+		}{
+			{ipLimiter, ip, "IP " + ip},
+			{userLimiter, strconv.Itoa(userID), "user " + strconv.Itoa(userID)},
+		}
+		for _, check := range checks {
+			if check.limiter == nil {
+				continue
+			}
+
+			allowed, retryAfter, firstRejection := check.limiter.Allow(check.key)
+			if allowed {
+				continue
+			}
+
+			if firstRejection {
+				services.ReportSuspiciousActivity(&models.SuspiciousActivity{
+					Type:    models.SuspiciousRateLimited,
+					UserID:  &userID,
+					IP:      ip,
+					Details: fmt.Sprintf("flag submission rate limit exceeded for %s", check.scope),
+				})
+			}
+
+			seconds := setRetryAfter(c, retryAfter)
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       fmt.Sprintf("Trop de soumissions, réessayez dans %d secondes", seconds),
+				"retry_after": seconds,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setRetryAfter renseigne l'en-tête Retry-After et retourne le délai en secondes
+func setRetryAfter(c *gin.Context, retryAfter time.Duration) int {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	return seconds
+}
+
+// GetSuspiciousActivities liste les activités suspectes consignées lors des
+// soumissions de flags (admin seulement)
+func GetSuspiciousActivities(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le paramètre limit doit être entre 1 et 500"})
+		return
+	}
+
+	activities, err := db.GetSuspiciousActivities(limit)
+	if err != nil {
+		log.Printf("Error fetching suspicious activities: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des activités suspectes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activities": activities})
+}
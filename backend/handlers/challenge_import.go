@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"backend/services"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportChallenges crée ou met à jour les challenges décrits par les fichiers
+// challenge.yml du dossier CHALLENGES_DIR et retourne les différences appliquées.
+// Avec ?dry_run=true, la base n'est pas modifiée (admin seulement).
+func ImportChallenges(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Paramètre dry_run invalide"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	dir := services.ChallengesDir()
+	results, err := services.ImportChallengeDir(dir, dryRun)
+	if err != nil {
+		log.Printf("Error importing challenges from %s: %v", dir, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la lecture du dossier des challenges"})
+		return
+	}
+
+	summary := make(map[string]int)
+	for _, result := range results {
+		summary[result.Action]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": dryRun,
+		"summary": summary,
+		"results": results,
+	})
+}
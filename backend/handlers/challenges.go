@@ -4,9 +4,13 @@ import (
 	"backend/db"
 	"backend/models"
 	"backend/services"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"log"
+	"time"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,12 +20,17 @@ type ChallengeResponse struct {
 	Description string `json:"description"`
 	Category    string `json:"category"`
 	Difficulty  string `json:"difficulty"`
-	Points      int    `json:"points"`
+	Points      int    `json:"points"` // Valeur courante (décroissante pour un challenge dynamique)
+	ScoringType string `json:"scoring_type"`
+	Solves      int    `json:"solves"`
 	DockerImage string `json:"docker_image"`
 	Port        int    `json:"port"`
 	CPULimit    string `json:"cpu_limit"`
 	MemoryLimit string `json:"memory_limit"`
 	CreatedAt   string `json:"created_at"`
+	Locked      bool   `json:"locked"` // Conditions de déblocage non remplies : description masquée
+
+	Unlock *models.ChallengeUnlock `json:"unlock,omitempty"`
 }
 
 
@@ -84,18 +93,30 @@ func GetStatsHandler(c *gin.Context) {
 }
 
 
-// GetChallengesHandler retourne tous les challenges actifs
+// GetChallengesHandler retourne tous les challenges actifs de la plateforme, ou ceux
+// de l'événement de la route sous /api/events/:slug
 func GetChallengesHandler(c *gin.Context) {
+	if event := scopedEvent(c); event != nil && !requireEventStarted(c, event) {
+		return
+	}
+
 	// Récupérer les paramètres de requête pour le filtrage
 	category := c.Query("category")
 	
 	// Construire la requête SQL de base
 	query := `
-		SELECT id, name, description, category, difficulty, points, docker_image, port, cpu_limit, memory_limit, created_at 
+		SELECT id, name, description, category, difficulty, points, IFNULL(scoring_type, 'static'),
+			(SELECT COUNT(*) FROM challenge_solves cs WHERE cs.challenge_id = challenges.id),
+			docker_image, port, cpu_limit, memory_limit, created_at 
 		FROM challenges 
-		WHERE is_active = 1`
+		WHERE is_active = 1 AND IFNULL(event_id, 0) = ?`
 	
-	var args []interface{}
+	args := []interface{}{scopedEventID(c)}
+
+	// Seuls les challenges publiés (fenêtre visible_from / visible_until) sont listés
+	visible, visibleArgs := db.VisibleChallengeFilter(time.Now())
+	query += " AND " + visible
+	args = append(args, visibleArgs...)
 	
 	// Ajouter le filtre par catégorie si spécifié
 	if category != "" {
@@ -124,6 +145,8 @@ func GetChallengesHandler(c *gin.Context) {
 			&challenge.Category,
 			&challenge.Difficulty,
 			&challenge.Points,
+			&challenge.ScoringType,
+			&challenge.Solves,
 			&challenge.DockerImage,
 			&challenge.Port,
 			&challenge.CPULimit,
@@ -144,6 +167,30 @@ func GetChallengesHandler(c *gin.Context) {
 		return
 	}
 
+	// Pendant le gel, valeurs et résolutions sont celles de la date de gel
+	points, solves, ok := frozenChallengeValues(c)
+	if !ok {
+		return
+	}
+	if points != nil {
+		for i := range challenges {
+			challenges[i].Points = points[challenges[i].ID]
+			challenges[i].Solves = solves[challenges[i].ID]
+		}
+	}
+
+	// Challenges verrouillés : masqués, ou listés sans leur description selon les réglages
+	challenges, ok = filterLockedChallenges(c, challenges,
+		func(challenge *ChallengeResponse) int { return challenge.ID },
+		func(challenge *ChallengeResponse, unlock *models.ChallengeUnlock) { challenge.Unlock = unlock },
+		func(challenge *ChallengeResponse) {
+			challenge.Locked = true
+			challenge.Description = ""
+		})
+	if !ok {
+		return
+	}
+
 	log.Printf("Nombre de challenges récupérés: %d pour la catégorie: %s", len(challenges), category)
 
 	// Retourner la réponse JSON
@@ -156,14 +203,40 @@ func GetChallengesHandler(c *gin.Context) {
 }
 
 
-// GetAllChallenges récupère tous les challenges actifs
+// GetAllChallenges récupère tous les challenges actifs de la plateforme, ou ceux de
+// l'événement de la route sous /api/events/:slug
 func GetAllChallenges(c *gin.Context) {
-	challenges, err := db.GetAllChallenges()
+	if event := scopedEvent(c); event != nil && !requireEventStarted(c, event) {
+		return
+	}
+
+	challenges, err := db.GetAllChallenges(scopedEventID(c))
 	if err != nil {
 		log.Printf("Error fetching challenges: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des challenges"})
 		return
 	}
+
+	points, _, ok := frozenChallengeValues(c)
+	if !ok {
+		return
+	}
+	if points != nil {
+		for i := range challenges {
+			challenges[i].Points = points[challenges[i].ID]
+		}
+	}
+
+	challenges, ok = filterLockedChallenges(c, challenges,
+		func(challenge *models.Challenge) int { return challenge.ID },
+		func(challenge *models.Challenge, unlock *models.ChallengeUnlock) { challenge.Unlock = unlock },
+		func(challenge *models.Challenge) {
+			challenge.Locked = true
+			challenge.Description = ""
+		})
+	if !ok {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"challenges": challenges})
 }
 
@@ -187,38 +260,138 @@ func GetChallengeByID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
 		return
 	}
+
+	// Les challenges d'un événement hébergé restent masqués aux joueurs jusqu'à son début
+	_, isAdmin := viewer(c)
+	if challenge.EventID != nil && !isAdmin {
+		event, err := services.ChallengeEvent(challenge)
+		if err != nil {
+			log.Printf("Error fetching event of challenge %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du challenge"})
+			return
+		}
+		if event.Status == models.EventScheduled {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+			return
+		}
+	}
+
+	// Un challenge hors de sa fenêtre de publication n'existe pas pour les joueurs
+	if !isAdmin && !services.ChallengeVisible(challenge, time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	// Conditions de déblocage : un challenge verrouillé est introuvable, ou affiché
+	// sans description, indices ni pièces jointes selon les réglages
+	unlock, err := db.GetChallengeUnlock(id)
+	if err != nil {
+		log.Printf("Error fetching unlock conditions of challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du challenge"})
+		return
+	}
+	if unlock.HasConditions() {
+		challenge.Unlock = unlock
+	}
+
+	checker, hide, ok := challengeLocks(c)
+	if !ok {
+		return
+	}
+	if checker != nil {
+		locked, err := checker.Locked(id, challenge.Scope())
+		if err != nil {
+			log.Printf("Error checking unlock of challenge %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des prérequis"})
+			return
+		}
+		if locked && hide {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+			return
+		}
+		if locked {
+			challenge.Locked = true
+			challenge.Description = ""
+			c.JSON(http.StatusOK, gin.H{"challenge": challenge})
+			return
+		}
+	}
+
+	// Métadonnées des indices (coût uniquement, le contenu passe par /api/challenges/:id/hints)
+	hints, err := db.GetChallengeHints(id)
+	if err != nil {
+		log.Printf("Error fetching hints of challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du challenge"})
+		return
+	}
+	challenge.Hints = hints
+
+	// Pièces jointes (le téléchargement passe par une URL signée, voir /api/attachments/:id/link)
+	attachments, err := db.GetChallengeAttachments(id)
+	if err != nil {
+		log.Printf("Error fetching attachments of challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du challenge"})
+		return
+	}
+	challenge.Attachments = attachments
 	
 	c.JSON(http.StatusOK, gin.H{"challenge": challenge})
 }
 
 // CreateChallenge crée un nouveau challenge (admin seulement)
 func CreateChallenge(c *gin.Context) {
-	var req struct {
-		Name        string `json:"name" binding:"required"`
-		Description string `json:"description"`
-		Port        int    `json:"port" binding:"required"`
-	}
-
+	var req models.CreateChallengeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
 		return
 	}
 
-	if req.Name == "" || req.Port == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Nom et port sont obligatoires"})
-		return
-	}
-
-	// Validation du port (plage valide)
-	if req.Port < 1024 || req.Port > 65535 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Le port doit être entre 1024 et 65535"})
+	// Les challenges reverse et forensics peuvent se limiter à des pièces jointes,
+	// sans image ni port
+	if req.DockerImage == "" && !services.IsFileCategory(req.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "L'image Docker est obligatoire pour cette catégorie"})
 		return
 	}
 
 	challenge := &models.Challenge{
 		Name:        req.Name,
 		Description: req.Description,
+		Category:    req.Category,
+		Difficulty:  req.Difficulty,
+		Points:      req.Points,
+		Flag:        strings.TrimSpace(req.Flag),
+		DockerImage: req.DockerImage,
 		Port:        req.Port,
+		CPULimit:    req.CPULimit,
+		MemoryLimit: req.MemoryLimit,
+		TimeLimit:   req.TimeLimit,
+		ScoringType: models.ScoringStatic,
+		IsActive:    true,
+	}
+	services.ApplyChallengeDefaults(challenge)
+
+	if err := services.ValidateChallengeSchedule(req.VisibleFrom, req.VisibleUntil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fenêtre de publication invalide", "details": err.Error()})
+		return
+	}
+	challenge.VisibleFrom = req.VisibleFrom
+	challenge.VisibleUntil = req.VisibleUntil
+
+	if req.EventID != nil {
+		eventID, ok := resolveChallengeEvent(c, *req.EventID)
+		if !ok {
+			return
+		}
+		challenge.EventID = eventID
+	}
+
+	if userID, exists := c.Get("user_id"); exists {
+		challenge.CreatedBy = userID.(int)
+	}
+
+	if err := services.ValidateChallenge(challenge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge invalide", "details": err.Error()})
+		return
 	}
 
 	id, err := db.CreateChallenge(challenge)
@@ -229,9 +402,174 @@ func CreateChallenge(c *gin.Context) {
 	}
 
 	challenge.ID = id
+	log.Printf("Challenge %d (%s) créé", challenge.ID, challenge.Name)
 	c.JSON(http.StatusCreated, gin.H{"challenge": challenge})
 }
 
+// UpdateChallenge modifie un challenge, y compris désactivé (admin seulement). Seuls
+// les champs renseignés sont modifiés ; is_active permet de (dés)activer le challenge.
+func UpdateChallenge(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var req models.UpdateChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	challenge, err := db.GetChallengeIncludingInactive(id)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	if req.Name != "" {
+		challenge.Name = req.Name
+	}
+	if req.Description != "" {
+		challenge.Description = req.Description
+	}
+	if req.Category != "" {
+		challenge.Category = req.Category
+	}
+	if req.Difficulty != "" {
+		challenge.Difficulty = req.Difficulty
+	}
+	if req.Flag != "" {
+		challenge.Flag = strings.TrimSpace(req.Flag)
+	}
+	if req.DockerImage != "" {
+		challenge.DockerImage = req.DockerImage
+	}
+	if req.Port != 0 {
+		challenge.Port = req.Port
+	}
+	if req.CPULimit != "" {
+		challenge.CPULimit = req.CPULimit
+	}
+	if req.MemoryLimit != "" {
+		challenge.MemoryLimit = req.MemoryLimit
+	}
+	if req.TimeLimit != 0 {
+		challenge.TimeLimit = req.TimeLimit
+	}
+	if req.IsActive != nil {
+		challenge.IsActive = *req.IsActive
+	}
+	if req.EventID != nil {
+		eventID, ok := resolveChallengeEvent(c, *req.EventID)
+		if !ok {
+			return
+		}
+		challenge.EventID = eventID
+	}
+
+	// Pour un challenge dynamique, points désigne la valeur initiale : la valeur
+	// courante est recalculée à partir du nombre de résolutions
+	if req.Points != 0 {
+		if challenge.ScoringType == models.ScoringDynamic {
+			challenge.InitialValue = req.Points
+		} else {
+			challenge.Points = req.Points
+		}
+	}
+
+	if challenge.DockerImage == "" && !services.IsFileCategory(challenge.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "L'image Docker est obligatoire pour cette catégorie"})
+		return
+	}
+	services.ApplyChallengeDefaults(challenge)
+
+	if err := services.ValidateChallenge(challenge); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge invalide", "details": err.Error()})
+		return
+	}
+
+	if err := db.UpdateChallenge(challenge); err != nil {
+		log.Printf("Error updating challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour du challenge"})
+		return
+	}
+
+	log.Printf("Challenge %d (%s) mis à jour (actif: %t)", challenge.ID, challenge.Name, challenge.IsActive)
+	c.JSON(http.StatusOK, gin.H{"challenge": challenge})
+}
+
+// resolveChallengeEvent vérifie l'événement hébergé auquel rattacher un challenge et
+// retourne son ID, nil pour 0 (challenge de la plateforme). En cas d'erreur, la
+// réponse est déjà envoyée.
+func resolveChallengeEvent(c *gin.Context, eventID int) (*int, bool) {
+	if eventID == 0 {
+		return nil, true
+	}
+
+	event, err := db.GetEventByID(eventID)
+	if err != nil {
+		log.Printf("Error fetching event %d: %v", eventID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'événement"})
+		return nil, false
+	}
+	if event == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Événement non trouvé"})
+		return nil, false
+	}
+	if event.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Seuls les événements hébergés (avec un slug) peuvent recevoir des challenges"})
+		return nil, false
+	}
+	return &event.ID, true
+}
+
+// UpdateChallengeScoring configure le calcul des points d'un challenge, statique ou
+// dynamique, et recalcule les scores de ses solveurs (admin seulement)
+func UpdateChallengeScoring(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var req models.UpdateChallengeScoringRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	challenge, err := db.GetChallengeByID(id)
+	if err != nil {
+		log.Printf("Error checking challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	value, err := db.UpdateChallengeScoring(id, &req)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidScoring) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Un challenge dynamique nécessite decay >= 1 et 1 <= minimum_value <= initial_value"})
+			return
+		}
+		log.Printf("Error updating scoring of challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour du score"})
+		return
+	}
+
+	log.Printf("Challenge %d scoring set to %s (current value: %d)", id, req.ScoringType, value)
+	c.JSON(http.StatusOK, gin.H{"message": "Score du challenge mis à jour", "points": value})
+}
+
 // DeleteChallenge supprime un challenge (admin seulement)
 func DeleteChallenge(c *gin.Context) {
 	idStr := c.Param("id")
@@ -309,13 +647,18 @@ func CreateInstance(c *gin.Context) {
 		return
 	}
 
-	// Si user_id n'est pas fourni, utiliser l'utilisateur connecté
+	// L'instance appartient à l'utilisateur connecté : seul un admin peut en créer
+	// une pour un autre joueur via user_id
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID utilisateur manquant"})
+		return
+	}
+	if req.UserID != 0 && req.UserID != userID.(int) && !c.GetBool("is_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Vous ne pouvez créer une instance que pour vous-même"})
+		return
+	}
 	if req.UserID == 0 {
-		userID, exists := c.Get("user_id")
-		if !exists {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "ID utilisateur manquant"})
-			return
-		}
 		req.UserID = userID.(int)
 	}
 
@@ -332,8 +675,20 @@ func CreateInstance(c *gin.Context) {
 		return
 	}
 
-	// Vérifier si l'utilisateur a déjà une instance active pour ce challenge
-	existingInstances, err := db.GetUserChallengeInstances(req.UserID, req.ChallengeID)
+	// Pas de nouvelle instance hors de la fenêtre de l'événement du challenge
+	event, ok := requireChallengeOpen(c, challenge, req.UserID)
+	if !ok {
+		return
+	}
+
+	if challenge.DockerImage == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ce challenge ne nécessite pas d'instance"})
+		return
+	}
+
+	// Vérifier si l'utilisateur (ou son équipe si les instances sont partagées)
+	// a déjà une instance active pour ce challenge
+	existingInstances, err := existingChallengeInstances(req.UserID, challenge)
 	if err != nil {
 		log.Printf("Error checking existing instances: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des instances existantes"})
@@ -348,46 +703,67 @@ func CreateInstance(c *gin.Context) {
 		return
 	}
 
-	// Créer l'instance
+	// Créer l'instance, dans le namespace de l'événement hébergé le cas échéant (le
+	// backend d'instances choisit sinon son namespace par défaut). Elle expire au bout
+	// du time_limit du challenge, la durée maximale que /extend peut rétablir.
 	instance := &models.Instance{
 		UserID:      req.UserID,
 		ChallengeID: req.ChallengeID,
 		Status:      "creating",
-		Namespace:   "ctf-instances",
+		ExpiresAt:   time.Now().Add(time.Duration(challenge.TimeLimit) * time.Second),
+	}
+	if event != nil {
+		instance.Namespace = event.Namespace
+	}
+
+	// Récupérer le backend d'instances sélectionné au démarrage
+	provider := instanceProvider(c)
+	if provider == nil {
+		return
 	}
 
-	// Initialiser le service Kubernetes
-	k8sService, err := services.NewKubernetesService()
+	// Enregistrer l'instance en base avant de créer ses ressources : son ID sert
+	// d'identifiant stable (labels, sélecteurs, nom du pod)
+	instanceID, err := db.CreateInstance(instance)
 	if err != nil {
-		log.Printf("Failed to initialize Kubernetes service: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'initialisation du service"})
+		log.Printf("Error saving instance to database: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la sauvegarde de l'instance"})
 		return
 	}
+	instance.ID = instanceID
 
-	// Créer l'instance dans Kubernetes
-	if err := k8sService.CreateChallengeInstance(instance, challenge); err != nil {
+	// Démarrer l'instance
+	if err := provider.CreateChallengeInstance(instance, challenge); err != nil {
 		log.Printf("Failed to create challenge instance: %v", err)
+		if deleteErr := db.DeleteInstance(instance.ID); deleteErr != nil {
+			log.Printf("Error deleting instance %d from database: %v", instance.ID, deleteErr)
+		}
+
+		if errors.Is(err, services.ErrPortCapacityExhausted) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Capacité maximale atteinte, aucun port disponible. Réessayez plus tard."})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'instance"})
 		return
 	}
 
-	// Sauvegarder l'instance en base de données
-	instanceID, err := db.CreateInstance(instance)
-	if err != nil {
-		log.Printf("Error saving instance to database: %v", err)
-		
-		// Essayer de nettoyer l'instance Kubernetes en cas d'erreur
-		if cleanupErr := k8sService.CleanupChallengeInstance(instance); cleanupErr != nil {
-			log.Printf("Failed to cleanup Kubernetes instance: %v", cleanupErr)
+	// Sauvegarder les ressources de l'instance en base de données
+	if err := db.UpdateInstanceResources(instance); err != nil {
+		log.Printf("Error saving instance %d resources to database: %v", instance.ID, err)
+
+		// Essayer de nettoyer l'instance en cas d'erreur
+		if cleanupErr := provider.CleanupChallengeInstance(instance); cleanupErr != nil {
+			log.Printf("Failed to cleanup instance: %v", cleanupErr)
 		}
-		
+		if deleteErr := db.DeleteInstance(instance.ID); deleteErr != nil {
+			log.Printf("Error deleting instance %d from database: %v", instance.ID, deleteErr)
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la sauvegarde de l'instance"})
 		return
 	}
 
-	// Mettre à jour l'ID de l'instance et le statut
-	instance.ID = instanceID
-	instance.Status = "running"
+	// Le statut reste "creating" jusqu'à ce que le pod soit prêt (voir WatchInstanceStatus)
 
 	// Préparer la réponse avec l'URL d'accès
 	response := gin.H{
@@ -404,6 +780,7 @@ func CreateInstance(c *gin.Context) {
 			"internal_port": instance.InternalPort,
 			"namespace":    instance.Namespace,
 			"created_at":   time.Now().Format(time.RFC3339),
+			"expires_at":   instance.ExpiresAt.Format(time.RFC3339),
 		},
 		"message": "Instance créée avec succès",
 	}
@@ -412,13 +789,48 @@ func CreateInstance(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
-// DeleteInstance supprime une instance
-func DeleteInstance(c *gin.Context) {
+// existingChallengeInstances récupère les instances actives d'un challenge accessibles à
+// l'utilisateur : les siennes, ou celles de son équipe si l'événement du challenge
+// partage les instances
+func existingChallengeInstances(userID int, challenge *models.Challenge) ([]models.Instance, error) {
+	settings, err := db.GetScopedSettings(challenge.Scope())
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Mode == models.ModeTeam && settings.ShareInstances {
+		teamID, err := db.GetUserTeamID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if teamID != 0 {
+			return db.GetTeamChallengeInstances(teamID, challenge.ID)
+		}
+	}
+
+	return db.GetUserChallengeInstances(userID, challenge.ID)
+}
+
+// instanceProvider retourne le backend d'instances configuré, ou envoie une erreur s'il
+// n'a pas pu être initialisé au démarrage
+func instanceProvider(c *gin.Context) services.InstanceProvider {
+	if services.Provider == nil {
+		log.Printf("Instance provider not initialized")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'initialisation du service"})
+		return nil
+	}
+	return services.Provider
+}
+
+// loadAuthorizedInstance récupère l'instance désignée par le paramètre :id et vérifie
+// que l'utilisateur connecté en est propriétaire ou administrateur. En cas d'échec,
+// la réponse d'erreur est déjà envoyée et nil est retourné.
+func loadAuthorizedInstance(c *gin.Context) *models.Instance {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
-		return
+		return nil
 	}
 
 	// Récupérer l'instance
@@ -426,43 +838,64 @@ func DeleteInstance(c *gin.Context) {
 	if err != nil {
 		log.Printf("Error fetching instance %d: %v", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'instance"})
-		return
+		return nil
 	}
 
 	if instance == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Instance non trouvée"})
-		return
+		return nil
 	}
 
-	// Vérifier les permissions (utilisateur propriétaire ou admin)
+	// Vérifier les permissions (utilisateur propriétaire, coéquipier si les instances sont partagées, ou admin)
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
-		return
+		return nil
 	}
 
 	isAdmin, _ := c.Get("is_admin")
-	if instance.UserID != userID.(int) && !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé"})
+	if instance.UserID != userID.(int) && isAdmin != true {
+		shared, err := teamSharesInstance(instance, userID.(int))
+		if err != nil {
+			log.Printf("Error checking team access to instance %d: %v", instance.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des permissions"})
+			return nil
+		}
+		if !shared {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Accès refusé"})
+			return nil
+		}
+	}
+
+	return instance
+}
+
+// isInstanceActive indique si une instance n'a pas encore été supprimée, expirée ou perdue
+func isInstanceActive(instance *models.Instance) bool {
+	return instance.Status != "deleted" && instance.Status != "expired" && instance.Status != "lost"
+}
+
+// DeleteInstance supprime une instance
+func DeleteInstance(c *gin.Context) {
+	instance := loadAuthorizedInstance(c)
+	if instance == nil {
 		return
 	}
 
-	// Supprimer l'instance de Kubernetes
-	k8sService, err := services.NewKubernetesService()
-	if err != nil {
-		log.Printf("Failed to initialize Kubernetes service: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'initialisation du service"})
+	// Supprimer les ressources de l'instance
+	provider := instanceProvider(c)
+	if provider == nil {
 		return
 	}
 
-	if err := k8sService.CleanupChallengeInstance(instance); err != nil {
-		log.Printf("Failed to delete Kubernetes instance: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de l'instance Kubernetes"})
+	if err := provider.CleanupChallengeInstance(instance); err != nil {
+		log.Printf("Failed to delete instance resources: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression des ressources de l'instance"})
 		return
 	}
 
 	// Supprimer l'instance de la base de données
-	if err := db.DeleteInstance(id); err != nil {
+	if err := db.DeleteInstance(instance.ID); err != nil {
 		log.Printf("Error deleting instance from database: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de l'instance"})
 		return
@@ -470,3 +903,154 @@ func DeleteInstance(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Instance supprimée avec succès"})
 }
+
+// ExtendInstance prolonge la durée de vie d'une instance, sans dépasser le time_limit du challenge
+func ExtendInstance(c *gin.Context) {
+	var req struct {
+		Minutes int `json:"minutes" binding:"omitempty,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	instance := loadAuthorizedInstance(c)
+	if instance == nil {
+		return
+	}
+
+	if !isInstanceActive(instance) {
+		c.JSON(http.StatusConflict, gin.H{"error": "L'instance n'est plus active"})
+		return
+	}
+
+	challenge, err := db.GetChallengeByID(instance.ChallengeID)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", instance.ChallengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return
+	}
+
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	// Par défaut, la prolongation correspond au time_limit complet du challenge
+	timeLimit := time.Duration(challenge.TimeLimit) * time.Second
+	extension := timeLimit
+	if req.Minutes > 0 {
+		extension = time.Duration(req.Minutes) * time.Minute
+	}
+
+	// La durée restante ne peut jamais dépasser le time_limit du challenge
+	now := time.Now()
+	expiresAt := instance.ExpiresAt
+	if expiresAt.Before(now) {
+		expiresAt = now
+	}
+	expiresAt = expiresAt.Add(extension)
+	if maxExpiresAt := now.Add(timeLimit); expiresAt.After(maxExpiresAt) {
+		expiresAt = maxExpiresAt
+	}
+
+	if !expiresAt.After(instance.ExpiresAt) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Durée maximale de l'instance déjà atteinte",
+			"expires_at": instance.ExpiresAt.Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := db.UpdateInstanceExpiry(instance.ID, expiresAt); err != nil {
+		log.Printf("Error extending instance %d: %v", instance.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la prolongation de l'instance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Instance prolongée avec succès",
+		"expires_at": expiresAt.Format(time.RFC3339),
+	})
+}
+
+// RestartInstance recrée le pod d'une instance
+func RestartInstance(c *gin.Context) {
+	instance := loadAuthorizedInstance(c)
+	if instance == nil {
+		return
+	}
+
+	if !isInstanceActive(instance) {
+		c.JSON(http.StatusConflict, gin.H{"error": "L'instance n'est plus active"})
+		return
+	}
+
+	provider := instanceProvider(c)
+	if provider == nil {
+		return
+	}
+
+	if err := provider.RestartChallengeInstance(instance); err != nil {
+		log.Printf("Failed to restart instance %d: %v", instance.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du redémarrage de l'instance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Instance redémarrée avec succès"})
+}
+
+// GetInstanceStatus retourne le statut d'une instance tel que vu par son backend (admin seulement)
+func GetInstanceStatus(c *gin.Context) {
+	instance := loadAuthorizedInstance(c)
+	if instance == nil {
+		return
+	}
+
+	provider := instanceProvider(c)
+	if provider == nil {
+		return
+	}
+
+	status, reason, err := provider.ChallengeInstanceStatus(instance)
+	if err != nil {
+		log.Printf("Failed to get status of instance %d: %v", instance.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du statut de l'instance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":            instance.ID,
+		"status":        status,
+		"status_reason": reason,
+	})
+}
+
+// GetInstanceLogs retourne les derniers logs d'une instance (admin seulement)
+func GetInstanceLogs(c *gin.Context) {
+	tail, err := strconv.ParseInt(c.DefaultQuery("tail", "200"), 10, 64)
+	if err != nil || tail < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Paramètre tail invalide"})
+		return
+	}
+
+	instance := loadAuthorizedInstance(c)
+	if instance == nil {
+		return
+	}
+
+	provider := instanceProvider(c)
+	if provider == nil {
+		return
+	}
+
+	logs, err := provider.ChallengeInstanceLogs(instance, tail)
+	if err != nil {
+		log.Printf("Failed to get logs of instance %d: %v", instance.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des logs de l'instance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": instance.ID, "logs": logs})
+}
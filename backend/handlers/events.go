@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventScope résout l'événement hébergé désigné par le paramètre :slug pour les
+// routes /api/events/:slug/...
+func EventScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		event, err := services.EventBySlug(c.Param("slug"))
+		if err != nil {
+			log.Printf("Error fetching event %s: %v", c.Param("slug"), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'événement"})
+			return
+		}
+		if event == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Événement non trouvé"})
+			return
+		}
+
+		c.Set("event", event)
+		c.Next()
+	}
+}
+
+// scopedEvent retourne l'événement de la route, nil hors de /api/events/:slug
+func scopedEvent(c *gin.Context) *models.Event {
+	if event, exists := c.Get("event"); exists {
+		return event.(*models.Event)
+	}
+	return nil
+}
+
+// scopedEventID retourne l'ID de l'événement de la route, 0 pour la plateforme
+func scopedEventID(c *gin.Context) int {
+	if event := scopedEvent(c); event != nil {
+		return event.ID
+	}
+	return 0
+}
+
+// requireChallengeAccessible vérifie que le joueur peut consulter le challenge et son
+// contenu (indices, pièces jointes) : challenge publié, événement commencé, inscription
+// s'il s'agit d'un événement hébergé et prérequis remplis. Les admins ne sont pas
+// concernés. Retourne l'événement du challenge (nil si aucun) ; en cas de refus, la
+// réponse est déjà envoyée.
+func requireChallengeAccessible(c *gin.Context, challenge *models.Challenge, userID int) (*models.Event, bool) {
+	// Sous /api/events/:slug, seuls les challenges de l'événement sont accessibles
+	if scope := scopedEvent(c); scope != nil && challenge.Scope() != scope.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return nil, false
+	}
+
+	event, err := services.ChallengeEvent(challenge)
+	if err != nil {
+		log.Printf("Error fetching event of challenge %d: %v", challenge.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'événement"})
+		return nil, false
+	}
+	if c.GetBool("is_admin") {
+		return event, true
+	}
+
+	// Un challenge hors de sa fenêtre de publication n'existe pas pour les joueurs
+	if !services.ChallengeVisible(challenge, time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return nil, false
+	}
+	if event != nil && !requireEventStarted(c, event) {
+		return nil, false
+	}
+
+	if event != nil && event.Slug != "" {
+		registered, err := db.IsRegisteredForEvent(event.ID, userID)
+		if err != nil {
+			log.Printf("Error checking registration of user %d to event %d: %v", userID, event.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification de l'inscription"})
+			return nil, false
+		}
+		if !registered {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Inscrivez-vous à l'événement pour participer", "event": event.Slug})
+			return nil, false
+		}
+	}
+
+	if !requireChallengeUnlocked(c, challenge, userID) {
+		return nil, false
+	}
+	return event, true
+}
+
+// requireChallengeOpen vérifie en plus de requireChallengeAccessible que l'événement
+// qui régit le challenge accepte les flags et les instances. Sans événement configuré,
+// la plateforme reste ouverte ; les admins ne sont pas concernés. Retourne l'événement
+// du challenge (nil si aucun) ; en cas de refus, la réponse est déjà envoyée.
+func requireChallengeOpen(c *gin.Context, challenge *models.Challenge, userID int) (*models.Event, bool) {
+	event, ok := requireChallengeAccessible(c, challenge, userID)
+	if !ok || event == nil || c.GetBool("is_admin") {
+		return event, ok
+	}
+
+	switch event.Status {
+	case models.EventPaused:
+		c.JSON(http.StatusForbidden, gin.H{"error": "L'événement est en pause"})
+		return nil, false
+	case models.EventEnded:
+		c.JSON(http.StatusForbidden, gin.H{"error": "L'événement est terminé"})
+		return nil, false
+	}
+	return event, true
+}
+
+// requireEventStarted masque le contenu d'un événement hébergé tant qu'il n'a pas
+// commencé. En cas de refus, la réponse est déjà envoyée.
+func requireEventStarted(c *gin.Context, event *models.Event) bool {
+	if event.Status == models.EventScheduled && !c.GetBool("is_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "L'événement n'a pas encore commencé", "start_time": event.StartTime})
+		return false
+	}
+	return true
+}
+
+// GetCurrentEvent retourne l'événement de la plateforme, ou celui de la route sous
+// /api/events/:slug, avec son état et l'heure du serveur
+func GetCurrentEvent(c *gin.Context) {
+	if event := scopedEvent(c); event != nil {
+		c.JSON(http.StatusOK, gin.H{"event": event, "server_time": time.Now().UTC()})
+		return
+	}
+
+	event, err := services.CurrentEvent()
+	if err != nil {
+		log.Printf("Error fetching current event: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'événement"})
+		return
+	}
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Aucun événement configuré"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": event, "server_time": time.Now().UTC()})
+}
+
+// GetHostedEvents liste les événements hébergés, pour choisir celui auquel participer
+func GetHostedEvents(c *gin.Context) {
+	events, err := db.GetEvents()
+	if err != nil {
+		log.Printf("Error fetching events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des événements"})
+		return
+	}
+
+	now := time.Now()
+	hosted := []models.Event{}
+	for _, event := range events {
+		if event.Slug == "" {
+			continue
+		}
+		services.SetEventStatus(&event, now)
+		event.Namespace = ""
+		hosted = append(hosted, event)
+	}
+	c.JSON(http.StatusOK, gin.H{"events": hosted})
+}
+
+// RegisterForEvent inscrit le joueur connecté à l'événement de la route, jusqu'à sa fin
+func RegisterForEvent(c *gin.Context) {
+	event := scopedEvent(c)
+	userID := c.GetInt("user_id")
+
+	if event.Status == models.EventEnded {
+		c.JSON(http.StatusForbidden, gin.H{"error": "L'événement est terminé"})
+		return
+	}
+
+	created, err := db.RegisterForEvent(event.ID, userID)
+	if err != nil {
+		log.Printf("Error registering user %d to event %d: %v", userID, event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'inscription"})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, gin.H{"message": "Déjà inscrit à l'événement"})
+		return
+	}
+
+	log.Printf("User %d registered to event %s", userID, event.Slug)
+	c.JSON(http.StatusCreated, gin.H{"message": "Inscription à l'événement confirmée"})
+}
+
+// GetEventRegistrations liste les inscrits d'un événement (admin seulement)
+func GetEventRegistrations(c *gin.Context) {
+	event := loadEvent(c)
+	if event == nil {
+		return
+	}
+
+	registrations, err := db.GetEventRegistrations(event.ID)
+	if err != nil {
+		log.Printf("Error fetching registrations of event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des inscriptions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"registrations": registrations, "count": len(registrations)})
+}
+
+// GetEvents liste les événements (admin seulement)
+func GetEvents(c *gin.Context) {
+	events, err := db.GetEvents()
+	if err != nil {
+		log.Printf("Error fetching events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des événements"})
+		return
+	}
+
+	now := time.Now()
+	for i := range events {
+		services.SetEventStatus(&events[i], now)
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// CreateEvent crée un événement (admin seulement). Sans slug, il devient l'événement
+// de la plateforme ; avec un slug, il est hébergé sous /api/events/:slug.
+func CreateEvent(c *gin.Context) {
+	var req models.EventRequest
+	if !bindEventRequest(c, &req, 0) {
+		return
+	}
+
+	event := &models.Event{
+		Slug:       req.Slug,
+		Name:       req.Name,
+		Namespace:  req.Namespace,
+		StartTime:  req.StartTime,
+		EndTime:    req.EndTime,
+		FreezeTime: req.FreezeTime,
+
+		Mode:           req.Mode,
+		ShareInstances: req.ShareInstances,
+	}
+	if err := db.CreateEvent(event); err != nil {
+		log.Printf("Error creating event: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'événement"})
+		return
+	}
+
+	services.SetEventStatus(event, time.Now())
+	log.Printf("Event %d (%s) created: %s -> %s", event.ID, event.Name, event.StartTime, event.EndTime)
+	c.JSON(http.StatusCreated, gin.H{"event": event})
+}
+
+// UpdateEvent remplace le slug, le nom, le namespace, les dates et le mode de jeu d'un
+// événement. Sans freeze_time, le classement public est dégelé. Le mode de jeu ne
+// change plus une fois l'événement commencé (admin seulement).
+func UpdateEvent(c *gin.Context) {
+	event := loadEvent(c)
+	if event == nil {
+		return
+	}
+
+	var req models.EventRequest
+	if !bindEventRequest(c, &req, event.ID) {
+		return
+	}
+	if event.Slug != "" && req.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le slug d'un événement hébergé ne peut pas être retiré"})
+		return
+	}
+
+	services.SetEventStatus(event, time.Now())
+	if event.Status != models.EventScheduled && !sameEventMode(event, &req) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Le mode de jeu d'un événement commencé ne peut plus être modifié"})
+		return
+	}
+
+	event.Slug = req.Slug
+	event.Name = req.Name
+	event.Namespace = req.Namespace
+	event.StartTime = req.StartTime
+	event.EndTime = req.EndTime
+	event.FreezeTime = req.FreezeTime
+	event.Mode = req.Mode
+	event.ShareInstances = req.ShareInstances
+	if err := db.UpdateEvent(event); err != nil {
+		log.Printf("Error updating event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de l'événement"})
+		return
+	}
+
+	services.SetEventStatus(event, time.Now())
+	c.JSON(http.StatusOK, gin.H{"event": event})
+}
+
+// PauseEvent suspend l'événement : flags et nouvelles instances sont refusés (admin seulement)
+func PauseEvent(c *gin.Context) {
+	event := loadEvent(c)
+	if event == nil {
+		return
+	}
+
+	if err := services.PauseEvent(event, time.Now()); err != nil {
+		if errors.Is(err, services.ErrEventNotRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": "L'événement n'est pas en cours"})
+			return
+		}
+		log.Printf("Error pausing event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise en pause de l'événement"})
+		return
+	}
+
+	log.Printf("Event %d paused", event.ID)
+	c.JSON(http.StatusOK, gin.H{"event": event})
+}
+
+// ResumeEvent reprend l'événement ; sa fin est repoussée de la durée de la pause (admin seulement)
+func ResumeEvent(c *gin.Context) {
+	event := loadEvent(c)
+	if event == nil {
+		return
+	}
+
+	if err := services.ResumeEvent(event, time.Now()); err != nil {
+		if errors.Is(err, services.ErrEventNotPaused) {
+			c.JSON(http.StatusConflict, gin.H{"error": "L'événement n'est pas en pause"})
+			return
+		}
+		log.Printf("Error resuming event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la reprise de l'événement"})
+		return
+	}
+
+	log.Printf("Event %d resumed, now ending at %s", event.ID, event.EndTime)
+	c.JSON(http.StatusOK, gin.H{"event": event})
+}
+
+// ExtendEvent repousse la fin de l'événement (admin seulement)
+func ExtendEvent(c *gin.Context) {
+	event := loadEvent(c)
+	if event == nil {
+		return
+	}
+
+	var req models.ExtendEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Durée invalide (ex: 30m, 2h)"})
+		return
+	}
+
+	if err := services.ExtendEvent(event, duration, time.Now()); err != nil {
+		log.Printf("Error extending event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la prolongation de l'événement"})
+		return
+	}
+
+	log.Printf("Event %d extended by %s, now ending at %s", event.ID, duration, event.EndTime)
+	c.JSON(http.StatusOK, gin.H{"event": event})
+}
+
+// bindEventRequest lit et vérifie les dates, le slug et le namespace d'un événement.
+// Le slug doit être libre, hors de l'événement eventID. En cas d'erreur, la réponse
+// est déjà envoyée.
+func bindEventRequest(c *gin.Context, req *models.EventRequest, eventID int) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return false
+	}
+	if err := services.ValidateEventRequest(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Événement invalide", "details": err.Error()})
+		return false
+	}
+	if req.Slug != "" {
+		existing, err := db.GetEventBySlug(req.Slug)
+		if err != nil {
+			log.Printf("Error fetching event %s: %v", req.Slug, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du slug"})
+			return false
+		}
+		if existing != nil && existing.ID != eventID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Ce slug est déjà utilisé par un autre événement"})
+			return false
+		}
+	}
+	if req.FreezeTime != nil && (req.FreezeTime.Before(req.StartTime) || req.FreezeTime.After(req.EndTime)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "freeze_time doit être compris entre start_time et end_time"})
+		return false
+	}
+	return true
+}
+
+// sameEventMode indique si req conserve le mode de jeu et le partage d'instances de event
+func sameEventMode(event *models.Event, req *models.EventRequest) bool {
+	if event.Mode != req.Mode || (event.ShareInstances == nil) != (req.ShareInstances == nil) {
+		return false
+	}
+	return event.ShareInstances == nil || *event.ShareInstances == *req.ShareInstances
+}
+
+// loadEvent récupère l'événement désigné par le paramètre :id et écrit la réponse
+// d'erreur s'il n'existe pas
+func loadEvent(c *gin.Context) *models.Event {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil
+	}
+
+	event, err := db.GetEventByID(id)
+	if err != nil {
+		log.Printf("Error fetching event %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'événement"})
+		return nil
+	}
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Événement non trouvé"})
+		return nil
+	}
+	return event
+}
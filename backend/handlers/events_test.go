@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestScheduledEventChallengeVisibleToAdmins(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+
+	start := time.Now().Add(time.Hour)
+	event := &models.Event{Slug: "yearly", Name: "Yearly", Namespace: "ctf-yearly", StartTime: start, EndTime: start.Add(24 * time.Hour)}
+	if err := db.CreateEvent(event); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	challengeID, err := db.CreateChallenge(&models.Challenge{Name: "Yearly pwn", Description: "d", Category: "Pwn",
+		Difficulty: "hard", Points: 300, Flag: "CTF{yearly}", ScoringType: models.ScoringStatic, IsActive: true, EventID: &event.ID})
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+
+	get := func(userID int, isAdmin bool) int {
+		return serveAs(t, userID, isAdmin, http.MethodGet, "/challenges/:id", fmt.Sprintf("/challenges/%d", challengeID), GetChallengeByID)
+	}
+	if code := get(alice, false); code != http.StatusNotFound {
+		t.Errorf("challenge of a scheduled event for a player = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := get(1, true); code != http.StatusOK {
+		t.Errorf("challenge of a scheduled event for an admin = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestHostedEventOwnGameMode(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("FLAG_SECRET", "test-secret")
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	team := &models.Team{Name: "pwners", InviteCode: "code", CaptainID: alice}
+	if err := db.CreateTeam(team); err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+
+	// La plateforme reste en mode individuel, l'événement se joue en équipe
+	event := &models.Event{Slug: "yearly", Name: "Yearly", Namespace: "ctf-yearly", Mode: models.ModeTeam,
+		StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)}
+	if err := db.CreateEvent(event); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	challengeID, err := db.CreateChallenge(&models.Challenge{Name: "Yearly pwn", Description: "d", Category: "Pwn",
+		Difficulty: "hard", Points: 300, Flag: "CTF{yearly}", ScoringType: models.ScoringStatic, IsActive: true, EventID: &event.ID})
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+	for _, userID := range []int{alice, bob} {
+		if _, err := db.RegisterForEvent(event.ID, userID); err != nil {
+			t.Fatalf("RegisterForEvent: %v", err)
+		}
+	}
+
+	submit := func(userID, challengeID int, flag string) int {
+		body := fmt.Sprintf(`{"challenge_id": %d, "flag": %q}`, challengeID, flag)
+		return serveJSON(t, userID, false, http.MethodPost, "/flags/submit", "/flags/submit", body, SubmitFlag).Code
+	}
+
+	// Sans équipe, bob marque sur la plateforme mais pas dans l'événement
+	if code := submit(bob, 1, "CTF{xss_reflected_pwned}"); code != http.StatusOK {
+		t.Errorf("platform flag of a player without team = %d, want %d", code, http.StatusOK)
+	}
+	if code := submit(bob, challengeID, "CTF{yearly}"); code != http.StatusForbidden {
+		t.Errorf("event flag of a player without team = %d, want %d", code, http.StatusForbidden)
+	}
+
+	if code := submit(alice, challengeID, "CTF{yearly}"); code != http.StatusOK {
+		t.Fatalf("event flag of a team member = %d, want %d", code, http.StatusOK)
+	}
+	solves, err := db.GetChallengeSolves(challengeID, nil)
+	if err != nil {
+		t.Fatalf("GetChallengeSolves: %v", err)
+	}
+	if len(solves) != 1 || solves[0].TeamID == nil || *solves[0].TeamID != team.ID {
+		t.Errorf("event solves = %+v, want one solve credited to team %d", solves, team.ID)
+	}
+
+	// Le mode d'un événement commencé est figé
+	body := fmt.Sprintf(`{"slug": "yearly", "name": "Yearly", "mode": "individual", "start_time": %q, "end_time": %q}`,
+		event.StartTime.Format(time.RFC3339), event.EndTime.Format(time.RFC3339))
+	path := fmt.Sprintf("/admin/events/%d", event.ID)
+	if w := serveJSON(t, 1, true, http.MethodPut, "/admin/events/:id", path, body, UpdateEvent); w.Code != http.StatusConflict {
+		t.Errorf("mode change of a running event = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportCTFtime retourne le classement complet au format d'import de CTFtime
+// ({"standings":[{"pos","team","score"}]}), gelé comme le classement public. Sous
+// /api/events/:slug, seuls les inscrits et les challenges de l'événement comptent.
+func ExportCTFtime(c *gin.Context) {
+	frozenAt, ok := scoreboardFreeze(c)
+	if !ok {
+		return
+	}
+
+	scoreboard, err := services.CTFtimeScoreboard(scopedEventID(c), frozenAt)
+	if err != nil {
+		log.Printf("Error exporting CTFtime scoreboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'export du classement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scoreboard)
+}
+
+// ExportSolves exporte l'historique complet des résolutions en JSON (par défaut) ou
+// en CSV avec ?format=csv (admin seulement). ?event=<slug> le limite aux challenges
+// d'un événement hébergé.
+func ExportSolves(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le paramètre format doit être json ou csv"})
+		return
+	}
+
+	var solves []models.ChallengeSolve
+	var err error
+	if slug := c.Query("event"); slug != "" {
+		var event *models.Event
+		if event, err = db.GetEventBySlug(slug); err != nil {
+			log.Printf("Error fetching event %q: %v", slug, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'événement"})
+			return
+		}
+		if event == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Événement non trouvé"})
+			return
+		}
+		solves, err = db.GetEventSolves(event.ID)
+	} else {
+		solves, err = db.GetAllSolves()
+	}
+	if err != nil {
+		log.Printf("Error exporting solves: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'export des résolutions"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=solves."+format)
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{"solves": solves, "count": len(solves)})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := services.WriteSolvesCSV(c.Writer, solves); err != nil {
+		log.Printf("Error writing solves CSV: %v", err)
+	}
+}
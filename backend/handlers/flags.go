@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitFlag vérifie un flag soumis pour un challenge et crédite les points
+// lors de la première résolution
+func SubmitFlag(c *gin.Context) {
+	var req models.SubmitFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	challenge, err := db.GetChallengeByID(req.ChallengeID)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", req.ChallengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return
+	}
+
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	// Flags refusés hors de la fenêtre de l'événement du challenge
+	event, ok := requireChallengeOpen(c, challenge, userID.(int))
+	if !ok {
+		return
+	}
+
+	// En mode équipe, la résolution est attribuée à l'équipe et les flags
+	// des instances de tous ses membres sont acceptés
+	teamID, ok := scoringTeamID(c, userID.(int), challenge.Scope())
+	if !ok {
+		return
+	}
+
+	// Trop de flags incorrects récents sur ce challenge : l'utilisateur doit patienter
+	lockout, err := services.FlagLockout(userID.(int), req.ChallengeID)
+	if err != nil {
+		log.Printf("Error checking flag lockout for user %d on challenge %d: %v", userID.(int), req.ChallengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du flag"})
+		return
+	}
+	if lockout > 0 {
+		seconds := setRetryAfter(c, lockout)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       fmt.Sprintf("Trop de flags incorrects pour ce challenge, réessayez dans %d secondes", seconds),
+			"retry_after": seconds,
+		})
+		return
+	}
+
+	var instanceFlags map[int]string
+	if teamID != nil {
+		instanceFlags, err = db.GetTeamInstanceFlags(*teamID, req.ChallengeID)
+	} else {
+		instanceFlags, err = db.GetUserInstanceFlags(userID.(int), req.ChallengeID)
+	}
+	if err != nil {
+		log.Printf("Error fetching instance flags for user %d on challenge %d: %v", userID.(int), req.ChallengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du flag"})
+		return
+	}
+
+	submittedFlag := strings.TrimSpace(req.Flag)
+	submission := &models.Submission{
+		UserID:        userID.(int),
+		ChallengeID:   req.ChallengeID,
+		TeamID:        teamID,
+		SubmittedFlag: submittedFlag,
+	}
+
+	// Les challenges instanciés sont validés avec le flag unique des instances du joueur,
+	// les autres avec le flag statique du challenge
+	if len(instanceFlags) > 0 {
+		for instanceID, flag := range instanceFlags {
+			if flagsMatch(submittedFlag, flag) {
+				id := instanceID
+				submission.IsValid = true
+				submission.InstanceID = &id
+				break
+			}
+		}
+	} else {
+		submission.IsValid = flagsMatch(submittedFlag, challenge.Flag)
+	}
+
+	// Flags supplémentaires du challenge : ceux qui le résolvent sont évalués avant
+	// les flags partiels
+	var matchedFlag *models.Flag
+	if !submission.IsValid {
+		flags, err := db.GetChallengeFlags(req.ChallengeID)
+		if err != nil {
+			log.Printf("Error fetching flags of challenge %d: %v", req.ChallengeID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du flag"})
+			return
+		}
+		for i := range flags {
+			if services.MatchFlag(&flags[i], submittedFlag) {
+				matchedFlag = &flags[i]
+				submission.IsValid = true
+				submission.FlagID = &matchedFlag.ID
+				break
+			}
+		}
+	}
+
+	awarded, err := db.RecordSubmission(submission)
+	if err != nil {
+		log.Printf("Error recording submission for user %d on challenge %d: %v", submission.UserID, req.ChallengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'enregistrement de la soumission"})
+		return
+	}
+
+	if !submission.IsValid {
+		services.ReportSharedFlag(submission)
+		services.ReportWrongFlag(submission, c.ClientIP())
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Flag incorrect"})
+		return
+	}
+
+	if matchedFlag != nil && matchedFlag.Points > 0 {
+		message := "Flag partiel correct !"
+		if !awarded {
+			message = "Flag partiel déjà validé"
+		} else {
+			log.Printf("Flag partiel %d du challenge %d validé par l'utilisateur %d (+%d points)",
+				matchedFlag.ID, req.ChallengeID, submission.UserID, submission.PointsAwarded)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"partial": true,
+			"message": message,
+			"points":  submission.PointsAwarded,
+		})
+		return
+	}
+
+	if !awarded {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Challenge déjà résolu",
+			"points":  0,
+		})
+		return
+	}
+
+	log.Printf("Challenge %d résolu par l'utilisateur %d (+%d points, rang %d, bonus %d)",
+		req.ChallengeID, submission.UserID, submission.PointsAwarded, submission.SolveRank, submission.Bonus)
+	// Pendant le gel du classement, le first blood n'est pas annoncé pour ne rien révéler
+	if submission.SolveRank == 1 && (event == nil || !event.Frozen) {
+		username, _ := c.Get("username")
+		announceFirstBlood(submission, challenge, fmt.Sprint(username))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "Flag correct !",
+		"points":      submission.PointsAwarded + submission.Bonus,
+		"bonus":       submission.Bonus,
+		"solve_rank":  submission.SolveRank,
+		"first_blood": submission.SolveRank == 1,
+	})
+}
+
+// announceFirstBlood publie une annonce pour la première résolution d'un challenge
+func announceFirstBlood(submission *models.Submission, challenge *models.Challenge, username string) {
+	solver := username
+	if submission.TeamID != nil {
+		team, err := db.GetTeamByID(*submission.TeamID)
+		if err != nil {
+			log.Printf("Error fetching team %d: %v", *submission.TeamID, err)
+		} else if team != nil {
+			solver = team.Name
+		}
+	}
+
+	announcement := &models.Announcement{
+		Type:        models.AnnouncementFirstBlood,
+		Message:     fmt.Sprintf("First blood ! %s a résolu %s en premier", solver, challenge.Name),
+		ChallengeID: &submission.ChallengeID,
+		UserID:      &submission.UserID,
+		TeamID:      submission.TeamID,
+		EventID:     challenge.EventID,
+	}
+	if err := db.CreateAnnouncement(announcement); err != nil {
+		log.Printf("Error creating first blood announcement for challenge %d: %v", challenge.ID, err)
+		return
+	}
+
+	log.Printf("First blood: %s on challenge %d", solver, challenge.ID)
+}
+
+// flagsMatch compare deux flags en temps constant
+func flagsMatch(submitted, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(submitted), []byte(expected)) == 1
+}
+
+// GetFlagReviews liste les soumissions signalées pour partage de flag (admin seulement)
+func GetFlagReviews(c *gin.Context) {
+	reviews, err := db.GetFlagReviews()
+	if err != nil {
+		log.Printf("Error fetching flag reviews: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des signalements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+// GetChallengeFlags liste les flags supplémentaires d'un challenge (admin seulement)
+func GetChallengeFlags(c *gin.Context) {
+	challenge := loadAdminChallenge(c)
+	if challenge == nil {
+		return
+	}
+
+	flags, err := db.GetChallengeFlags(challenge.ID)
+	if err != nil {
+		log.Printf("Error fetching flags of challenge %d: %v", challenge.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flags": flags})
+}
+
+// CreateFlag ajoute un flag supplémentaire, statique ou regex, à un challenge. Avec
+// des points, le flag est partiel et ne résout pas le challenge (admin seulement).
+func CreateFlag(c *gin.Context) {
+	challenge := loadAdminChallenge(c)
+	if challenge == nil {
+		return
+	}
+
+	var req models.CreateFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	flag := &models.Flag{
+		ChallengeID:   challenge.ID,
+		Type:          req.Type,
+		Content:       strings.TrimSpace(req.Content),
+		CaseSensitive: req.CaseSensitive == nil || *req.CaseSensitive,
+		Points:        req.Points,
+	}
+	if err := services.ValidateFlag(flag); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Flag invalide", "details": err.Error()})
+		return
+	}
+
+	if err := db.CreateFlag(flag); err != nil {
+		log.Printf("Error creating flag for challenge %d: %v", challenge.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création du flag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"flag": flag})
+}
+
+// DeleteFlag supprime un flag supplémentaire et retire les points partiels qu'il a
+// rapportés (admin seulement)
+func DeleteFlag(c *gin.Context) {
+	flagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	flag, err := db.GetFlagByID(flagID)
+	if err != nil {
+		log.Printf("Error fetching flag %d: %v", flagID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du flag"})
+		return
+	}
+	if flag == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flag non trouvé"})
+		return
+	}
+
+	if err := db.DeleteFlag(flagID); err != nil {
+		log.Printf("Error deleting flag %d: %v", flagID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression du flag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Flag supprimé avec succès"})
+}
+
+// loadAdminChallenge récupère le challenge désigné par le paramètre :id, même désactivé,
+// et écrit la réponse d'erreur s'il n'existe pas
+func loadAdminChallenge(c *gin.Context) *models.Challenge {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return nil
+	}
+
+	challenge, err := db.GetChallengeIncludingInactive(id)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return nil
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return nil
+	}
+	return challenge
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChallengeHints liste les indices d'un challenge : le contenu des indices débloqués
+// par le joueur (ou son équipe) et le coût des autres
+func GetChallengeHints(c *gin.Context) {
+	challengeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	challenge, err := db.GetChallengeByID(challengeID)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", challengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+	if _, ok := requireChallengeAccessible(c, challenge, userID.(int)); !ok {
+		return
+	}
+
+	teamID, ok := scoringTeamID(c, userID.(int), challenge.Scope())
+	if !ok {
+		return
+	}
+
+	hints, err := db.GetPlayerHints(challengeID, userID.(int), teamID)
+	if err != nil {
+		log.Printf("Error fetching hints of challenge %d: %v", challengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des indices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hints": hints})
+}
+
+// UnlockHint débloque un indice en déduisant son coût du score du joueur ou de son équipe
+func UnlockHint(c *gin.Context) {
+	hintID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	hint, err := db.GetHintByID(hintID)
+	if err != nil {
+		log.Printf("Error fetching hint %d: %v", hintID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'indice"})
+		return
+	}
+	if hint == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Indice non trouvé"})
+		return
+	}
+
+	challenge, err := db.GetChallengeByID(hint.ChallengeID)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", hint.ChallengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+	if _, ok := requireChallengeOpen(c, challenge, userID.(int)); !ok {
+		return
+	}
+
+	teamID, ok := scoringTeamID(c, userID.(int), challenge.Scope())
+	if !ok {
+		return
+	}
+
+	cost, err := db.UnlockHint(hint, userID.(int), teamID)
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientScore) {
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": "Score insuffisant pour débloquer cet indice", "cost": hint.Cost})
+			return
+		}
+		log.Printf("Error unlocking hint %d for user %d: %v", hintID, userID.(int), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du déblocage de l'indice"})
+		return
+	}
+
+	hint.Unlocked = true
+	c.JSON(http.StatusOK, gin.H{"hint": hint, "cost": cost})
+}
+
+// CreateHint ajoute un indice à un challenge (admin seulement)
+func CreateHint(c *gin.Context) {
+	challengeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var req models.CreateHintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	challenge, err := db.GetChallengeByID(challengeID)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", challengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	hint := &models.Hint{ChallengeID: challengeID, Content: req.Content, Cost: req.Cost}
+	if err := db.CreateHint(hint); err != nil {
+		log.Printf("Error creating hint for challenge %d: %v", challengeID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'indice"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"hint": hint})
+}
+
+// DeleteHint supprime un indice et rembourse ceux qui l'avaient débloqué (admin seulement)
+func DeleteHint(c *gin.Context) {
+	hintID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	hint, err := db.GetHintByID(hintID)
+	if err != nil {
+		log.Printf("Error fetching hint %d: %v", hintID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'indice"})
+		return
+	}
+	if hint == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Indice non trouvé"})
+		return
+	}
+
+	if err := db.DeleteHint(hintID); err != nil {
+		log.Printf("Error deleting hint %d: %v", hintID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la suppression de l'indice"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Indice supprimé avec succès"})
+}
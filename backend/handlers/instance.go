@@ -16,7 +16,7 @@ func GetUserInstances(c *gin.Context) {
 		return
 	}
 
-	rows, err := db.DB.Query("SELECT id, user_id, challenge_id, container_name, started_at, expires_at, status FROM instances WHERE user_id=?", userID)
+	rows, err := db.DB.Query("SELECT id, user_id, challenge_id, container_name, started_at, expires_at, status, IFNULL(status_reason, '') FROM instances WHERE user_id=?", userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -26,7 +26,7 @@ func GetUserInstances(c *gin.Context) {
 	instances := []models.Instance{}
 	for rows.Next() {
 		var inst models.Instance
-		err := rows.Scan(&inst.ID, &inst.UserID, &inst.ChallengeID, &inst.ContainerName, &inst.StartedAt, &inst.ExpiresAt, &inst.Status)
+		err := rows.Scan(&inst.ID, &inst.UserID, &inst.ChallengeID, &inst.ContainerName, &inst.StartedAt, &inst.ExpiresAt, &inst.Status, &inst.StatusReason)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// setupTestProvider remplace le backend d'instances par un backend en mémoire
+func setupTestProvider(t *testing.T) {
+	t.Helper()
+	t.Setenv("FLAG_SECRET", "test-secret")
+	previous := services.Provider
+	services.Provider = services.NewMemoryProvider()
+	t.Cleanup(func() { services.Provider = previous })
+}
+
+func TestInstanceLifecycle(t *testing.T) {
+	setupTestDB(t)
+	setupTestProvider(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	w := serveJSON(t, alice, false, http.MethodPost, "/instances", "/instances", `{"challenge_id": 1}`, CreateInstance)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("CreateInstance = %d: %s", w.Code, w.Body)
+	}
+	var created struct {
+		Instance struct {
+			ID int `json:"id"`
+		} `json:"instance"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode CreateInstance response: %v", err)
+	}
+	id := created.Instance.ID
+
+	// L'instance expire au bout du time_limit du challenge
+	challenge, err := db.GetChallengeByID(1)
+	if err != nil || challenge == nil {
+		t.Fatalf("GetChallengeByID(1): %v", err)
+	}
+	instance, err := db.GetInstanceByID(id)
+	if err != nil || instance == nil {
+		t.Fatalf("GetInstanceByID(%d): %v", id, err)
+	}
+	timeLimit := time.Duration(challenge.TimeLimit) * time.Second
+	if remaining := time.Until(instance.ExpiresAt); remaining > timeLimit || remaining < timeLimit-time.Minute {
+		t.Errorf("new instance expires in %s, want the challenge time_limit %s", remaining, timeLimit)
+	}
+
+	path := func(action string) string { return fmt.Sprintf("/instances/%d%s", id, action) }
+	extend := func(userID int) int {
+		return serveAs(t, userID, false, http.MethodPost, "/instances/:id/extend", path("/extend"), ExtendInstance)
+	}
+	restart := func(userID int) int {
+		return serveAs(t, userID, false, http.MethodPost, "/instances/:id/restart", path("/restart"), RestartInstance)
+	}
+	remove := func(userID int) int {
+		return serveAs(t, userID, false, http.MethodDelete, "/instances/:id", path(""), DeleteInstance)
+	}
+
+	// La prolongation rétablit le time_limit complet, sans le dépasser
+	if code := extend(alice); code != http.StatusOK {
+		t.Errorf("extend = %d, want %d", code, http.StatusOK)
+	}
+	if instance, _ = db.GetInstanceByID(id); time.Until(instance.ExpiresAt) > timeLimit {
+		t.Errorf("extended instance expires in %s, beyond the time_limit %s", time.Until(instance.ExpiresAt), timeLimit)
+	}
+
+	for name, call := range map[string]func(int) int{"extend": extend, "restart": restart, "delete": remove} {
+		if code := call(bob); code != http.StatusForbidden {
+			t.Errorf("%s by another player = %d, want %d", name, code, http.StatusForbidden)
+		}
+	}
+
+	if code := restart(alice); code != http.StatusOK {
+		t.Errorf("restart = %d, want %d", code, http.StatusOK)
+	}
+	if code := remove(alice); code != http.StatusOK {
+		t.Errorf("delete = %d, want %d", code, http.StatusOK)
+	}
+	if code := extend(alice); code != http.StatusConflict {
+		t.Errorf("extend after delete = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestCreateInstanceForAnotherPlayerRefused(t *testing.T) {
+	setupTestDB(t)
+	setupTestProvider(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+
+	// Le challenge 2 nécessite le challenge 1, résolu par bob seulement
+	if err := db.SetChallengeUnlock(2, &models.ChallengeUnlock{Prerequisites: []int{1}, Mode: models.UnlockAll}, nil); err != nil {
+		t.Fatalf("SetChallengeUnlock: %v", err)
+	}
+	submission := &models.Submission{UserID: bob, ChallengeID: 1, SubmittedFlag: "CTF{xss_reflected_pwned}", IsValid: true}
+	if _, err := db.RecordSubmission(submission); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"user_id": %d, "challenge_id": 2}`, bob)
+	if w := serveJSON(t, alice, false, http.MethodPost, "/instances", "/instances", body, CreateInstance); w.Code != http.StatusForbidden {
+		t.Errorf("CreateInstance for another player = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+	}
+	instances, err := db.GetUserInstances(bob)
+	if err != nil {
+		t.Fatalf("GetUserInstances: %v", err)
+	}
+	if len(instances) != 0 {
+		t.Errorf("bob has %d instances after alice's request, want 0", len(instances))
+	}
+
+	if w := serveJSON(t, 1, true, http.MethodPost, "/instances", "/instances", body, CreateInstance); w.Code != http.StatusCreated {
+		t.Errorf("CreateInstance by an admin for bob = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+}
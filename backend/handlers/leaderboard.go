@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paginationParams lit les paramètres page et limit. En cas d'erreur, la réponse
+// est déjà envoyée et ok vaut false.
+func paginationParams(c *gin.Context) (page, limit int, ok bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Paramètre page invalide"})
+		return 0, 0, false
+	}
+
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le paramètre limit doit être entre 1 et 100"})
+		return 0, 0, false
+	}
+
+	return page, limit, true
+}
+
+// scoreboardFreeze retourne la date de gel du classement de l'événement de la
+// plateforme, ou de celui de la route sous /api/events/:slug. Les admins voient
+// toujours le classement en direct. En cas d'erreur, la réponse est déjà envoyée et
+// ok vaut false.
+func scoreboardFreeze(c *gin.Context) (frozenAt *time.Time, ok bool) {
+	if c.GetBool("is_admin") {
+		return nil, true
+	}
+	if event := scopedEvent(c); event != nil {
+		if event.Frozen {
+			return event.FreezeTime, true
+		}
+		return nil, true
+	}
+
+	frozenAt, err := services.ScoreboardFreeze()
+	if err != nil {
+		log.Printf("Error fetching current event: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'événement"})
+		return nil, false
+	}
+	return frozenAt, true
+}
+
+// frozenChallengeValues retourne, pendant le gel du classement, la valeur et le
+// nombre de résolutions des challenges à la date de gel. Hors gel (et pour les
+// admins), les deux maps sont nil. En cas d'erreur, la réponse est déjà envoyée.
+func frozenChallengeValues(c *gin.Context) (points, solves map[int]int, ok bool) {
+	frozenAt, ok := scoreboardFreeze(c)
+	if !ok || frozenAt == nil {
+		return nil, nil, ok
+	}
+
+	points, solves, err := db.GetChallengeValuesAt(scopedEventID(c), *frozenAt)
+	if err != nil {
+		log.Printf("Error fetching frozen challenge values: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des challenges"})
+		return nil, nil, false
+	}
+	return points, solves, true
+}
+
+// GetLeaderboard retourne le classement paginé des joueurs, gelé pendant la fin de
+// l'événement (en direct pour les admins). Sous /api/events/:slug, seuls les inscrits
+// et les challenges de l'événement comptent.
+func GetLeaderboard(c *gin.Context) {
+	page, limit, ok := paginationParams(c)
+	if !ok {
+		return
+	}
+	frozenAt, ok := scoreboardFreeze(c)
+	if !ok {
+		return
+	}
+
+	var entries []models.LeaderboardEntry
+	var total int
+	var err error
+	if event := scopedEvent(c); event != nil {
+		entries, total, err = db.GetEventLeaderboard(event.ID, limit, (page-1)*limit, frozenAt)
+	} else {
+		entries, total, err = db.GetLeaderboard(limit, (page-1)*limit, frozenAt)
+	}
+	if err != nil {
+		log.Printf("Error fetching leaderboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du classement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"leaderboard": entries,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"frozen":      frozenAt != nil,
+	})
+}
+
+// GetTeamLeaderboard retourne le classement paginé des équipes, gelé comme celui des joueurs
+func GetTeamLeaderboard(c *gin.Context) {
+	page, limit, ok := paginationParams(c)
+	if !ok {
+		return
+	}
+	frozenAt, ok := scoreboardFreeze(c)
+	if !ok {
+		return
+	}
+
+	var entries []models.TeamLeaderboardEntry
+	var total int
+	var err error
+	if event := scopedEvent(c); event != nil {
+		entries, total, err = db.GetEventTeamLeaderboard(event.ID, limit, (page-1)*limit, frozenAt)
+	} else {
+		entries, total, err = db.GetTeamLeaderboard(limit, (page-1)*limit, frozenAt)
+	}
+	if err != nil {
+		log.Printf("Error fetching team leaderboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du classement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"leaderboard": entries,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"frozen":      frozenAt != nil,
+	})
+}
+
+// GetTopScoreboard retourne l'évolution du score des N premiers joueurs
+func GetTopScoreboard(c *gin.Context) {
+	n, err := strconv.Atoi(c.DefaultQuery("n", "10"))
+	if err != nil || n < 1 || n > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le paramètre n doit être entre 1 et 50"})
+		return
+	}
+
+	frozenAt, ok := scoreboardFreeze(c)
+	if !ok {
+		return
+	}
+
+	var series []models.ScoreSeries
+	if event := scopedEvent(c); event != nil {
+		series, err = db.GetEventTopScoreSeries(event.ID, n, frozenAt)
+	} else {
+		series, err = db.GetTopScoreSeries(n, frozenAt)
+	}
+	if err != nil {
+		log.Printf("Error fetching scoreboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du scoreboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"series": series, "frozen": frozenAt != nil})
+}
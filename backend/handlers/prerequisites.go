@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// viewer identifie le visiteur d'une route, authentifiée ou publique : l'utilisateur
+// de la session s'il est connecté (0 sinon) et son statut d'admin
+func viewer(c *gin.Context) (int, bool) {
+	if userID, exists := c.Get("user_id"); exists {
+		return userID.(int), c.GetBool("is_admin")
+	}
+
+	session := sessions.Default(c)
+	userID, _ := session.Get("user_id").(int)
+	isAdmin, _ := session.Get("is_admin").(bool)
+	return userID, isAdmin
+}
+
+// challengeLocks prépare l'évaluation des verrous d'une liste de challenges pour le
+// visiteur. Retourne nil pour un admin, qui voit tout. En cas d'erreur, la réponse est
+// déjà envoyée.
+func challengeLocks(c *gin.Context) (checker *services.UnlockChecker, hide bool, ok bool) {
+	userID, isAdmin := viewer(c)
+	if isAdmin {
+		return nil, false, true
+	}
+
+	checker, err := services.NewUnlockChecker(userID)
+	if err == nil {
+		hide, err = services.HideLocked()
+	}
+	if err != nil {
+		log.Printf("Error loading challenge unlocks for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des prérequis"})
+		return nil, false, false
+	}
+	return checker, hide, true
+}
+
+// filterLockedChallenges applique au visiteur les verrous d'une liste de challenges
+// de la portée de la route : les challenges verrouillés sont retirés ou passés à lock,
+// qui les marque verrouillés et masque leur description. unlock reçoit les conditions
+// de déblocage de chaque challenge conservé. En cas d'erreur, la réponse est déjà
+// envoyée.
+func filterLockedChallenges[T any](c *gin.Context, challenges []T, id func(*T) int,
+	unlock func(*T, *models.ChallengeUnlock), lock func(*T)) ([]T, bool) {
+	checker, hide, ok := challengeLocks(c)
+	if !ok {
+		return nil, false
+	}
+	if checker == nil {
+		return challenges, true
+	}
+
+	visible := challenges[:0]
+	for i := range challenges {
+		challenge := &challenges[i]
+		locked, err := checker.Locked(id(challenge), scopedEventID(c))
+		if err != nil {
+			log.Printf("Error checking unlock of challenge %d: %v", id(challenge), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des prérequis"})
+			return nil, false
+		}
+		if locked && hide {
+			continue
+		}
+		unlock(challenge, checker.Unlock(id(challenge)))
+		if locked {
+			lock(challenge)
+		}
+		visible = append(visible, *challenge)
+	}
+	return visible, true
+}
+
+// requireChallengeUnlocked refuse l'accès à un challenge dont le joueur ne remplit pas
+// les conditions de déblocage. En cas de refus, la réponse est déjà envoyée.
+func requireChallengeUnlocked(c *gin.Context, challenge *models.Challenge, userID int) bool {
+	checker, err := services.NewUnlockChecker(userID)
+	if err != nil {
+		log.Printf("Error loading challenge unlocks for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des prérequis"})
+		return false
+	}
+
+	locked, err := checker.Locked(challenge.ID, challenge.Scope())
+	if err != nil {
+		log.Printf("Error checking unlock of challenge %d for user %d: %v", challenge.ID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la vérification des prérequis"})
+		return false
+	}
+	if locked {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  "Ce challenge est verrouillé : remplissez d'abord ses conditions de déblocage",
+			"unlock": checker.Unlock(challenge.ID),
+		})
+		return false
+	}
+	return true
+}
+
+// UpdateChallengePrerequisites remplace les conditions de déblocage d'un challenge :
+// ses prérequis, leur mode (all : tous, any : au moins un) et un score minimal dans
+// son événement (admin seulement). Les cycles sont refusés.
+func UpdateChallengePrerequisites(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var req models.UpdatePrerequisitesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	challenge, err := db.GetChallengeIncludingInactive(id)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	unlock := &models.ChallengeUnlock{Prerequisites: req.Prerequisites, Mode: req.Mode, MinScore: req.MinScore}
+	if err := services.SetChallengeUnlock(challenge, unlock); err != nil {
+		if errors.Is(err, services.ErrInvalidUnlock) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Prérequis invalides", "details": err.Error()})
+			return
+		}
+		log.Printf("Error updating prerequisites of challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour des prérequis"})
+		return
+	}
+
+	log.Printf("Challenge %d unlock set to %s of %v with min score %d", id, unlock.Mode, unlock.Prerequisites, unlock.MinScore)
+	c.JSON(http.StatusOK, gin.H{"unlock": unlock})
+}
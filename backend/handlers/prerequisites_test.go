@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "ctf.sqlite"))
+	t.Setenv("ATTACHMENT_SECRET", "test-secret")
+	db.InitDB()
+	t.Cleanup(db.CloseDB)
+}
+
+func createTestUser(t *testing.T, username string) int {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", PasswordHash: "x", Role: "user"}
+	if err := db.InsertUser(user); err != nil {
+		t.Fatalf("InsertUser(%s): %v", username, err)
+	}
+	return user.ID
+}
+
+// serveAs appelle handler sur la route donnée comme le ferait AuthRequired pour
+// l'utilisateur userID, et retourne le code de la réponse
+func serveAs(t *testing.T, userID int, isAdmin bool, method, route, path string, handler gin.HandlerFunc) int {
+	t.Helper()
+	return serveJSON(t, userID, isAdmin, method, route, path, "", handler).Code
+}
+
+// serveJSON appelle handler comme serveAs, avec body comme corps JSON s'il n'est pas
+// vide, et retourne la réponse complète
+func serveJSON(t *testing.T, userID int, isAdmin bool, method, route, path, body string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("is_admin", isAdmin)
+	}, handler)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLockedChallengeContentRefused(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+
+	// Le challenge 2 nécessite le challenge 1
+	if err := db.SetChallengeUnlock(2, &models.ChallengeUnlock{Prerequisites: []int{1}, Mode: models.UnlockAll}, nil); err != nil {
+		t.Fatalf("SetChallengeUnlock: %v", err)
+	}
+	hint := &models.Hint{ChallengeID: 2, Content: "regardez le token", Cost: 10}
+	if err := db.CreateHint(hint); err != nil {
+		t.Fatalf("CreateHint: %v", err)
+	}
+	attachment := &models.Attachment{ChallengeID: 2, Filename: "source.zip", StorageKey: "2/source.zip"}
+	if err := db.CreateAttachment(attachment); err != nil {
+		t.Fatalf("CreateAttachment: %v", err)
+	}
+
+	requests := []struct {
+		name, method, route, path string
+		handler                   gin.HandlerFunc
+	}{
+		{"hints", http.MethodGet, "/challenges/:id/hints", "/challenges/2/hints", GetChallengeHints},
+		{"unlock hint", http.MethodPost, "/hints/:id/unlock", fmt.Sprintf("/hints/%d/unlock", hint.ID), UnlockHint},
+		{"attachment link", http.MethodGet, "/attachments/:id/link", fmt.Sprintf("/attachments/%d/link", attachment.ID), GetAttachmentLink},
+	}
+
+	for _, req := range requests {
+		if code := serveAs(t, alice, false, req.method, req.route, req.path, req.handler); code != http.StatusForbidden {
+			t.Errorf("%s of a locked challenge = %d, want %d", req.name, code, http.StatusForbidden)
+		}
+		if code := serveAs(t, 1, true, req.method, req.route, req.path, req.handler); code == http.StatusForbidden {
+			t.Errorf("%s refused to an admin", req.name)
+		}
+	}
+
+	submission := &models.Submission{UserID: alice, ChallengeID: 1, SubmittedFlag: "CTF{xss_reflected_pwned}", IsValid: true}
+	if _, err := db.RecordSubmission(submission); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+	for _, req := range requests {
+		if code := serveAs(t, alice, false, req.method, req.route, req.path, req.handler); code != http.StatusOK {
+			t.Errorf("%s once unlocked = %d, want %d", req.name, code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"backend/services"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReconcileReport retourne, sans rien modifier, les écarts entre la base et le cluster (admin seulement)
+func GetReconcileReport(c *gin.Context) {
+	reconcile(c, true)
+}
+
+// RunReconcile supprime les ressources orphelines et marque les instances perdues (admin seulement)
+func RunReconcile(c *gin.Context) {
+	reconcile(c, false)
+}
+
+func reconcile(c *gin.Context, dryRun bool) {
+	k8sService, ok := services.Provider.(*services.KubernetesService)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Réconciliation disponible uniquement avec le driver Kubernetes"})
+		return
+	}
+
+	report, err := k8sService.Reconcile(dryRun)
+	if err != nil {
+		log.Printf("Reconcile failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la réconciliation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"backend/services"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateChallengeSchedule remplace la fenêtre de publication d'un challenge
+// (admin seulement). Une publication future sera annoncée aux joueurs.
+func UpdateChallengeSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	var req models.UpdateChallengeScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+	if err := services.ValidateChallengeSchedule(req.VisibleFrom, req.VisibleUntil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fenêtre de publication invalide", "details": err.Error()})
+		return
+	}
+
+	challenge, err := db.GetChallengeIncludingInactive(id)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du challenge"})
+		return
+	}
+	if challenge == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	if err := db.SetChallengeSchedule(id, req.VisibleFrom, req.VisibleUntil); err != nil {
+		log.Printf("Error updating schedule of challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour de la publication"})
+		return
+	}
+
+	challenge.VisibleFrom = req.VisibleFrom
+	challenge.VisibleUntil = req.VisibleUntil
+	log.Printf("Challenge %d scheduled from %v until %v", id, req.VisibleFrom, req.VisibleUntil)
+	c.JSON(http.StatusOK, gin.H{"challenge": challenge})
+}
+
+// GetUpcomingReleases liste les challenges dont la publication est programmée, du
+// plus proche au plus lointain (admin seulement)
+func GetUpcomingReleases(c *gin.Context) {
+	now := time.Now()
+	releases, err := db.GetUpcomingReleases(now)
+	if err != nil {
+		log.Printf("Error fetching upcoming releases: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des publications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"releases": releases, "count": len(releases), "server_time": now.UTC()})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUnreleasedChallengeContentHidden(t *testing.T) {
+	setupTestDB(t)
+	alice := createTestUser(t, "alice")
+
+	hint := &models.Hint{ChallengeID: 2, Content: "regardez le token", Cost: 0}
+	if err := db.CreateHint(hint); err != nil {
+		t.Fatalf("CreateHint: %v", err)
+	}
+	attachment := &models.Attachment{ChallengeID: 2, Filename: "source.zip", StorageKey: "2/source.zip"}
+	if err := db.CreateAttachment(attachment); err != nil {
+		t.Fatalf("CreateAttachment: %v", err)
+	}
+
+	hints := func() int {
+		return serveAs(t, alice, false, http.MethodGet, "/challenges/:id/hints", "/challenges/2/hints", GetChallengeHints)
+	}
+	unlock := func() int {
+		return serveAs(t, alice, false, http.MethodPost, "/hints/:id/unlock", fmt.Sprintf("/hints/%d/unlock", hint.ID), UnlockHint)
+	}
+	link := func() int {
+		return serveAs(t, alice, false, http.MethodGet, "/attachments/:id/link", fmt.Sprintf("/attachments/%d/link", attachment.ID), GetAttachmentLink)
+	}
+
+	windows := []struct {
+		name        string
+		from, until *time.Time
+	}{
+		{"not yet released", ptrTime(time.Now().Add(time.Hour)), nil},
+		{"withdrawn", nil, ptrTime(time.Now().Add(-time.Hour))},
+	}
+	for _, window := range windows {
+		if err := db.SetChallengeSchedule(2, window.from, window.until); err != nil {
+			t.Fatalf("SetChallengeSchedule: %v", err)
+		}
+		if code := hints(); code != http.StatusNotFound {
+			t.Errorf("%s: hints = %d, want %d", window.name, code, http.StatusNotFound)
+		}
+		if code := unlock(); code != http.StatusNotFound {
+			t.Errorf("%s: unlock hint = %d, want %d", window.name, code, http.StatusNotFound)
+		}
+		if code := link(); code != http.StatusNotFound {
+			t.Errorf("%s: attachment link = %d, want %d", window.name, code, http.StatusNotFound)
+		}
+	}
+
+	if err := db.SetChallengeSchedule(2, ptrTime(time.Now().Add(-time.Hour)), nil); err != nil {
+		t.Fatalf("SetChallengeSchedule: %v", err)
+	}
+	if code := hints(); code != http.StatusOK {
+		t.Errorf("released: hints = %d, want %d", code, http.StatusOK)
+	}
+	if code := link(); code != http.StatusOK {
+		t.Errorf("released: attachment link = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"backend/db"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEventSettings retourne les réglages de l'événement (admin seulement)
+func GetEventSettings(c *gin.Context) {
+	settings, err := db.GetEventSettings()
+	if err != nil {
+		log.Printf("Error fetching event settings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des réglages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+// UpdateEventSettings modifie les réglages de l'événement (admin seulement). Les
+// réglages absents de la requête gardent leur valeur actuelle.
+func UpdateEventSettings(c *gin.Context) {
+	var req struct {
+		Mode             *string `json:"mode" binding:"omitempty,oneof=individual team"`
+		ShareInstances   *bool   `json:"share_instances"`
+		BloodBonuses     *[]int  `json:"blood_bonuses" binding:"omitempty,max=3,dive,min=0"`
+		LockedChallenges *string `json:"locked_challenges" binding:"omitempty,oneof=locked hidden"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	settings, err := db.GetEventSettings()
+	if err != nil {
+		log.Printf("Error fetching event settings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des réglages"})
+		return
+	}
+	if req.Mode != nil {
+		settings.Mode = *req.Mode
+	}
+	if req.ShareInstances != nil {
+		settings.ShareInstances = *req.ShareInstances
+	}
+	if req.BloodBonuses != nil {
+		settings.BloodBonuses = *req.BloodBonuses
+	}
+	if req.LockedChallenges != nil {
+		settings.LockedChallenges = *req.LockedChallenges
+	}
+
+	if err := db.UpdateEventSettings(settings); err != nil {
+		log.Printf("Error updating event settings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la mise à jour des réglages"})
+		return
+	}
+
+	log.Printf("Event settings updated: mode=%s, share_instances=%t, blood_bonuses=%v, locked_challenges=%s",
+		settings.Mode, settings.ShareInstances, settings.BloodBonuses, settings.LockedChallenges)
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
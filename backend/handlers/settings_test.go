@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateEventSettingsKeepsOmittedSettings(t *testing.T) {
+	setupTestDB(t)
+
+	initial := &models.EventSettings{
+		Mode:             models.ModeIndividual,
+		BloodBonuses:     []int{50, 30, 10},
+		LockedChallenges: models.LockedHidden,
+	}
+	if err := db.UpdateEventSettings(initial); err != nil {
+		t.Fatalf("UpdateEventSettings: %v", err)
+	}
+
+	update := func(body string) int {
+		t.Helper()
+		return serveJSON(t, 1, true, http.MethodPut, "/admin/settings", "/admin/settings", body, UpdateEventSettings).Code
+	}
+
+	if code := update(`{"mode": "team", "share_instances": true}`); code != http.StatusOK {
+		t.Fatalf("update of mode = %d, want %d", code, http.StatusOK)
+	}
+	settings, err := db.GetEventSettings()
+	if err != nil {
+		t.Fatalf("GetEventSettings: %v", err)
+	}
+	want := &models.EventSettings{
+		Mode:             models.ModeTeam,
+		ShareInstances:   true,
+		BloodBonuses:     []int{50, 30, 10},
+		LockedChallenges: models.LockedHidden,
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("settings after a partial update = %+v, want %+v", settings, want)
+	}
+
+	if code := update(`{"blood_bonuses": []}`); code != http.StatusOK {
+		t.Fatalf("clearing blood bonuses = %d, want %d", code, http.StatusOK)
+	}
+	if settings, err = db.GetEventSettings(); err != nil {
+		t.Fatalf("GetEventSettings: %v", err)
+	}
+	if len(settings.BloodBonuses) != 0 || settings.Mode != models.ModeTeam {
+		t.Errorf("settings after clearing blood bonuses = %+v, want no bonus in team mode", settings)
+	}
+
+	for _, body := range []string{`{"mode": "solo"}`, `{"blood_bonuses": [50, 30, 10, 5]}`, `{"blood_bonuses": [-1]}`} {
+		if code := update(body); code != http.StatusBadRequest {
+			t.Errorf("update %s = %d, want %d", body, code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/services"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChallengeSolves retourne les résolutions d'un challenge dans l'ordre, avec leur
+// bonus, jusqu'au gel du classement
+func GetChallengeSolves(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	challenge, err := db.GetChallengeByID(id)
+	if err != nil {
+		log.Printf("Error fetching challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du challenge"})
+		return
+	}
+	// Un challenge hors de sa fenêtre de publication n'existe pas pour les joueurs
+	if _, isAdmin := viewer(c); challenge == nil || (!isAdmin && !services.ChallengeVisible(challenge, time.Now())) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge non trouvé"})
+		return
+	}
+
+	frozenAt, ok := scoreboardFreeze(c)
+	if !ok {
+		return
+	}
+
+	solves, err := db.GetChallengeSolves(id, frozenAt)
+	if err != nil {
+		log.Printf("Error fetching solves of challenge %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des résolutions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"solves": solves, "count": len(solves)})
+}
+
+// GetUserSolves retourne les challenges résolus par un utilisateur et son rang sur
+// chacun, avec son score, tels qu'au gel du classement
+func GetUserSolves(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+
+	user, err := db.GetUserByID(id)
+	if err != nil {
+		log.Printf("Error fetching user %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'utilisateur"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
+		return
+	}
+
+	// Pendant le gel, le profil ne trahit pas les résolutions récentes
+	frozenAt, ok := scoreboardFreeze(c)
+	if !ok {
+		return
+	}
+
+	solves, err := db.GetUserSolves(id, frozenAt)
+	if err != nil {
+		log.Printf("Error fetching solves of user %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des résolutions"})
+		return
+	}
+
+	score := user.Score
+	if frozenAt != nil {
+		if score, err = db.GetScopedScore(0, id, nil, frozenAt); err != nil {
+			log.Printf("Error fetching frozen score of user %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des résolutions"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"score":    score,
+		"solves":   solves,
+	})
+}
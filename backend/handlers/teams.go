@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"backend/db"
+	"backend/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newInviteCode génère un code d'invitation aléatoire
+func newInviteCode() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// loadUserTeam récupère l'équipe de l'utilisateur connecté. En cas d'échec (dont
+// l'absence d'équipe), la réponse d'erreur est déjà envoyée et nil est retourné.
+func loadUserTeam(c *gin.Context) (int, *models.Team) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return 0, nil
+	}
+
+	teamID, err := db.GetUserTeamID(userID.(int))
+	if err != nil {
+		log.Printf("Error fetching team of user %d: %v", userID.(int), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'équipe"})
+		return 0, nil
+	}
+	if teamID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vous n'appartenez à aucune équipe"})
+		return 0, nil
+	}
+
+	team, err := db.GetTeamByID(teamID)
+	if err != nil || team == nil {
+		log.Printf("Error fetching team %d: %v", teamID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'équipe"})
+		return 0, nil
+	}
+
+	return userID.(int), team
+}
+
+// loadCaptainTeam récupère l'équipe de l'utilisateur connecté et vérifie qu'il en est le capitaine
+func loadCaptainTeam(c *gin.Context) (int, *models.Team) {
+	userID, team := loadUserTeam(c)
+	if team == nil {
+		return 0, nil
+	}
+	if team.CaptainID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Action réservée au capitaine de l'équipe"})
+		return 0, nil
+	}
+	return userID, team
+}
+
+// CreateTeam crée une équipe dont l'utilisateur connecté devient le capitaine
+func CreateTeam(c *gin.Context) {
+	var req models.CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		log.Printf("Error generating invite code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'équipe"})
+		return
+	}
+
+	team := &models.Team{
+		Name:       req.Name,
+		InviteCode: code,
+		CaptainID:  userID.(int),
+	}
+	if err := db.CreateTeam(team); err != nil {
+		switch {
+		case errors.Is(err, db.ErrAlreadyInTeam):
+			c.JSON(http.StatusConflict, gin.H{"error": "Vous appartenez déjà à une équipe"})
+		case errors.Is(err, db.ErrTeamNameTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "Nom d'équipe déjà utilisé"})
+		default:
+			log.Printf("Error creating team: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la création de l'équipe"})
+		}
+		return
+	}
+
+	log.Printf("Équipe %q créée par l'utilisateur %d", team.Name, team.CaptainID)
+	c.JSON(http.StatusCreated, gin.H{"message": "Équipe créée avec succès", "team": team})
+}
+
+// JoinTeam fait rejoindre à l'utilisateur connecté l'équipe correspondant au code d'invitation
+func JoinTeam(c *gin.Context) {
+	var req models.JoinTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Utilisateur non authentifié"})
+		return
+	}
+
+	team, err := db.GetTeamByInviteCode(req.InviteCode)
+	if err != nil {
+		log.Printf("Error fetching team by invite code: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'équipe"})
+		return
+	}
+	if team == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Code d'invitation invalide"})
+		return
+	}
+
+	if err := db.JoinTeam(userID.(int), team.ID); err != nil {
+		if errors.Is(err, db.ErrAlreadyInTeam) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Vous appartenez déjà à une équipe"})
+			return
+		}
+		log.Printf("Error joining team %d: %v", team.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'ajout à l'équipe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vous avez rejoint l'équipe " + team.Name, "team_id": team.ID})
+}
+
+// LeaveTeam retire l'utilisateur connecté de son équipe
+func LeaveTeam(c *gin.Context) {
+	userID, team := loadUserTeam(c)
+	if team == nil {
+		return
+	}
+
+	if err := db.RemoveTeamMember(team.ID, userID); err != nil {
+		log.Printf("Error leaving team %d: %v", team.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du départ de l'équipe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vous avez quitté l'équipe"})
+}
+
+// GetMyTeam retourne l'équipe de l'utilisateur connecté avec ses membres et son code d'invitation
+func GetMyTeam(c *gin.Context) {
+	_, team := loadUserTeam(c)
+	if team == nil {
+		return
+	}
+
+	members, err := db.GetTeamMembers(team.ID)
+	if err != nil {
+		log.Printf("Error fetching members of team %d: %v", team.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des membres"})
+		return
+	}
+	team.Members = members
+
+	c.JSON(http.StatusOK, gin.H{"team": team})
+}
+
+// RemoveTeamMember exclut un membre de l'équipe (capitaine seulement)
+func RemoveTeamMember(c *gin.Context) {
+	captainID, team := loadCaptainTeam(c)
+	if team == nil {
+		return
+	}
+
+	memberID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		return
+	}
+	if memberID == captainID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Le capitaine ne peut pas s'exclure, utilisez /api/teams/leave"})
+		return
+	}
+
+	if err := db.RemoveTeamMember(team.ID, memberID); err != nil {
+		if errors.Is(err, db.ErrNotInTeam) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Membre non trouvé dans l'équipe"})
+			return
+		}
+		log.Printf("Error removing user %d from team %d: %v", memberID, team.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de l'exclusion du membre"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Membre exclu de l'équipe"})
+}
+
+// TransferTeamCaptain transmet le rôle de capitaine à un autre membre (capitaine seulement)
+func TransferTeamCaptain(c *gin.Context) {
+	_, team := loadCaptainTeam(c)
+	if team == nil {
+		return
+	}
+
+	var req models.TransferCaptainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Données invalides", "details": err.Error()})
+		return
+	}
+
+	if err := db.TransferTeamCaptain(team.ID, req.UserID); err != nil {
+		if errors.Is(err, db.ErrNotInTeam) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Membre non trouvé dans l'équipe"})
+			return
+		}
+		log.Printf("Error transferring captain of team %d: %v", team.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du changement de capitaine"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Capitaine mis à jour"})
+}
+
+// RegenerateTeamInviteCode invalide le code d'invitation et en génère un nouveau (capitaine seulement)
+func RegenerateTeamInviteCode(c *gin.Context) {
+	_, team := loadCaptainTeam(c)
+	if team == nil {
+		return
+	}
+
+	code, err := newInviteCode()
+	if err == nil {
+		err = db.UpdateTeamInviteCode(team.ID, code)
+	}
+	if err != nil {
+		log.Printf("Error regenerating invite code of team %d: %v", team.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors du changement de code d'invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invite_code": code})
+}
+
+// scoringTeamID retourne l'équipe à laquelle attribuer les points de l'utilisateur
+// sur un challenge de l'événement eventID (0 pour la plateforme) si son mode est le
+// mode équipe, ou nil en mode individuel. Un joueur sans équipe ne peut pas marquer
+// en mode équipe : la réponse d'erreur est alors envoyée et ok vaut false.
+func scoringTeamID(c *gin.Context, userID, eventID int) (teamID *int, ok bool) {
+	settings, err := db.GetScopedSettings(eventID)
+	if err != nil {
+		log.Printf("Error fetching event settings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des réglages"})
+		return nil, false
+	}
+	if settings.Mode != models.ModeTeam {
+		return nil, true
+	}
+
+	id, err := db.GetUserTeamID(userID)
+	if err != nil {
+		log.Printf("Error fetching team of user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération de l'équipe"})
+		return nil, false
+	}
+	if id == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Vous devez rejoindre une équipe pour participer"})
+		return nil, false
+	}
+	return &id, true
+}
+
+// teamSharesInstance indique si userID peut accéder à une instance d'un autre joueur
+// parce qu'ils sont coéquipiers et que l'événement du challenge de l'instance partage
+// les instances
+func teamSharesInstance(instance *models.Instance, userID int) (bool, error) {
+	challenge, err := db.GetChallengeIncludingInactive(instance.ChallengeID)
+	if err != nil || challenge == nil {
+		return false, err
+	}
+	settings, err := db.GetScopedSettings(challenge.Scope())
+	if err != nil {
+		return false, err
+	}
+	if settings.Mode != models.ModeTeam || !settings.ShareInstances {
+		return false, nil
+	}
+
+	ownerTeam, err := db.GetUserTeamID(instance.UserID)
+	if err != nil || ownerTeam == 0 {
+		return false, err
+	}
+	userTeam, err := db.GetUserTeamID(userID)
+	if err != nil {
+		return false, err
+	}
+	return ownerTeam == userTeam, nil
+}
@@ -3,9 +3,17 @@ package main
 import (
 	"backend/db"
 	"backend/handlers"
+	"backend/services"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/sessions"
@@ -20,11 +28,34 @@ func main() {
 		log.Fatalf("Erreur lors du chargement du fichier .env: %v", err)
 	}
 
+	// Sans secret, les flags d'instance seraient calculables à partir du nom du pod
+	if err := services.CheckFlagSecret(); err != nil {
+		log.Fatalf("Configuration invalide: %v", err)
+	}
+	// Sans secret, les URLs de téléchargement des pièces jointes pourraient être forgées
+	if err := services.CheckAttachmentSecret(); err != nil {
+		log.Fatalf("Configuration invalide: %v", err)
+	}
+
 	db.InitDB()
 	defer db.CloseDB()
 
+	if err := services.InitInstanceProvider(); err != nil {
+		log.Printf("Erreur lors de l'initialisation du backend d'instances: %v", err)
+	}
+
+	if err := services.InitStorage(); err != nil {
+		log.Printf("Erreur lors de l'initialisation du stockage des pièces jointes: %v", err)
+	}
+
 	r := gin.Default()
 
+	// Proxys de confiance (liste séparée par des virgules) : sans eux, l'IP du client
+	// est celle de la connexion et les en-têtes X-Forwarded-For sont ignorés
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("Configuration invalide de TRUSTED_PROXIES: %v", err)
+	}
+
 	// Configuration des sessions
 	store := cookie.NewStore([]byte(os.Getenv("SESSION_SECRET")))
 	store.Options(sessions.Options{
@@ -75,9 +106,39 @@ func main() {
 	// Challenges (lecture seule pour tous)
 	r.GET("/challenges", handlers.GetAllChallenges)
 	r.GET("/challenges/:id", handlers.GetChallengeByID)
+	r.GET("/challenges/:id/solves", handlers.GetChallengeSolves)
+
+	// Téléchargement des pièces jointes (URL signée, voir /api/attachments/:id/link)
+	r.GET("/attachments/:id/download", handlers.DownloadAttachment)
 	
 	// 🆕 NOUVELLE ROUTE : Statistiques des challenges (publique)
 	r.GET("/api/stats", handlers.GetStatsHandler)
+
+	// Classement (public)
+	r.GET("/api/leaderboard", handlers.GetLeaderboard)
+	r.GET("/api/leaderboard/teams", handlers.GetTeamLeaderboard)
+	r.GET("/api/scoreboard/top", handlers.GetTopScoreboard)
+	r.GET("/api/export/ctftime", handlers.ExportCTFtime)
+	r.GET("/api/users/:id/solves", handlers.GetUserSolves)
+
+	// Événement en cours (horaires, état, gel du classement)
+	r.GET("/api/event", handlers.GetCurrentEvent)
+
+	// Événements hébergés : challenges, classements et inscriptions propres à chacun
+	r.GET("/api/events", handlers.GetHostedEvents)
+	events := r.Group("/api/events/:slug")
+	events.Use(handlers.EventScope())
+	{
+		events.GET("", handlers.GetCurrentEvent)
+		events.GET("/challenges", handlers.GetChallengesHandler)
+		events.GET("/leaderboard", handlers.GetLeaderboard)
+		events.GET("/leaderboard/teams", handlers.GetTeamLeaderboard)
+		events.GET("/scoreboard/top", handlers.GetTopScoreboard)
+		events.GET("/export/ctftime", handlers.ExportCTFtime)
+	}
+
+	// Annonces (first bloods...)
+	r.GET("/api/announcements", handlers.GetAnnouncements)
 	
 	// ✨ ROUTE POUR INITIALISER LES CHALLENGES (décommentée si nécessaire)
 	//r.POST("/challenges/init-chall", handlers.InitDefaultChallengesHandler)
@@ -87,6 +148,10 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// Limiteur partagé par les deux routes de soumission : un joueur ne double pas son
+	// quota en alternant /api et /api/events/:slug
+	flagLimit := handlers.FlagRateLimit()
+
 	// --------------------------------------------
 	// Protected routes
 	// --------------------------------------------
@@ -99,7 +164,38 @@ func main() {
 		// Challenge instances
 		protected.GET("/instances/:user_id", handlers.GetUserInstances)
 		protected.POST("/instances", handlers.CreateInstance)
+		protected.DELETE("/instances/:id", handlers.DeleteInstance)
+		protected.POST("/instances/:id/extend", handlers.ExtendInstance)
+		protected.POST("/instances/:id/restart", handlers.RestartInstance)
 		protected.GET("/challenges", handlers.GetChallengesHandler)
+
+		// Flag submission
+		protected.POST("/flags/submit", flagLimit, handlers.SubmitFlag)
+
+		// Hints
+		protected.GET("/challenges/:id/hints", handlers.GetChallengeHints)
+		protected.POST("/hints/:id/unlock", handlers.UnlockHint)
+
+		// Attachments
+		protected.GET("/attachments/:id/link", handlers.GetAttachmentLink)
+
+		// Teams
+		protected.GET("/teams/me", handlers.GetMyTeam)
+		protected.POST("/teams", handlers.CreateTeam)
+		protected.POST("/teams/join", handlers.JoinTeam)
+		protected.POST("/teams/leave", handlers.LeaveTeam)
+		protected.DELETE("/teams/members/:user_id", handlers.RemoveTeamMember)
+		protected.POST("/teams/captain", handlers.TransferTeamCaptain)
+		protected.POST("/teams/invite-code", handlers.RegenerateTeamInviteCode)
+	}
+
+	// Participation à un événement hébergé
+	eventParticipation := r.Group("/api/events/:slug")
+	eventParticipation.Use(handlers.AuthMiddleware(), handlers.EventScope())
+	{
+		eventParticipation.POST("/register", handlers.RegisterForEvent)
+		eventParticipation.POST("/instances", handlers.CreateInstance)
+		eventParticipation.POST("/flags/submit", flagLimit, handlers.SubmitFlag)
 	}
 	// --------------------------------------------
 	// Admin routes
@@ -109,14 +205,158 @@ func main() {
 	{
 		// Challenge management
 		admin.POST("/challenges", handlers.CreateChallenge)
+		admin.PUT("/challenges/:id", handlers.UpdateChallenge)
 		admin.DELETE("/challenges/:id", handlers.DeleteChallenge)
+		admin.POST("/challenges/import", handlers.ImportChallenges)
+		admin.PUT("/challenges/:id/scoring", handlers.UpdateChallengeScoring)
+		admin.PUT("/challenges/:id/prerequisites", handlers.UpdateChallengePrerequisites)
+		admin.PUT("/challenges/:id/schedule", handlers.UpdateChallengeSchedule)
+		admin.GET("/releases", handlers.GetUpcomingReleases)
+		admin.POST("/challenges/:id/hints", handlers.CreateHint)
+		admin.DELETE("/hints/:id", handlers.DeleteHint)
+		admin.GET("/challenges/:id/flags", handlers.GetChallengeFlags)
+		admin.POST("/challenges/:id/flags", handlers.CreateFlag)
+		admin.DELETE("/flags/:id", handlers.DeleteFlag)
+		admin.POST("/challenges/:id/attachments", handlers.UploadAttachment)
+		admin.DELETE("/attachments/:id", handlers.DeleteAttachment)
+
+		// Event settings (individual or team mode)
+		admin.GET("/settings", handlers.GetEventSettings)
+		admin.PUT("/settings", handlers.UpdateEventSettings)
+
+		// Event lifecycle
+		admin.GET("/events", handlers.GetEvents)
+		admin.POST("/events", handlers.CreateEvent)
+		admin.PUT("/events/:id", handlers.UpdateEvent)
+		admin.POST("/events/:id/pause", handlers.PauseEvent)
+		admin.POST("/events/:id/resume", handlers.ResumeEvent)
+		admin.POST("/events/:id/extend", handlers.ExtendEvent)
+		admin.GET("/events/:id/registrations", handlers.GetEventRegistrations)
+
+		// Live scoreboard, not affected by the freeze
+		admin.GET("/leaderboard", handlers.GetLeaderboard)
+		admin.GET("/leaderboard/teams", handlers.GetTeamLeaderboard)
+		admin.GET("/scoreboard/top", handlers.GetTopScoreboard)
+
+		// Exports: live CTFtime standings and full solve history
+		admin.GET("/export/ctftime", handlers.ExportCTFtime)
+		admin.GET("/export/solves", handlers.ExportSolves)
+
+		// Flag sharing reviews
+		admin.GET("/flag-reviews", handlers.GetFlagReviews)
+		admin.GET("/suspicious-activity", handlers.GetSuspiciousActivities)
+
+		// Instance diagnostics
+		admin.GET("/instances/:id/status", handlers.GetInstanceStatus)
+		admin.GET("/instances/:id/logs", handlers.GetInstanceLogs)
+
+		// Reconciliation between the instances table and the cluster
+		admin.GET("/reconcile", handlers.GetReconcileReport)
+		admin.POST("/reconcile", handlers.RunReconcile)
 	}
 
+	// --------------------------------------------
+	// Background workers
+	// --------------------------------------------
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	startBackgroundWorkers(ctx, &workers)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	log.Printf("CTF Platform starting on port %s", port)
-	r.Run(":" + port)
-}
\ No newline at end of file
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	go func() {
+		log.Printf("CTF Platform starting on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Erreur du serveur HTTP: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Arrêt du serveur...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Erreur lors de l'arrêt du serveur: %v", err)
+	}
+
+	workers.Wait()
+}
+
+// startBackgroundWorkers lance l'annonce des challenges publiés, le nettoyage
+// périodique des instances expirées et, avec le driver Kubernetes, le suivi de leur
+// état et la réconciliation avec le cluster. Les intervalles sont configurables avec
+// RELEASE_INTERVAL (par défaut 30s), REAPER_INTERVAL (par défaut 1m) et
+// RECONCILE_INTERVAL (par défaut 10m).
+func startBackgroundWorkers(ctx context.Context, workers *sync.WaitGroup) {
+	interval := durationFromEnv("REAPER_INTERVAL", time.Minute)
+	reconcileInterval := durationFromEnv("RECONCILE_INTERVAL", 10*time.Minute)
+
+	announcer := services.NewReleaseAnnouncer(durationFromEnv("RELEASE_INTERVAL", 30*time.Second))
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		announcer.Run(ctx)
+	}()
+
+	if services.Provider == nil {
+		log.Println("Background workers disabled: no instance provider")
+		return
+	}
+
+	reaper := services.NewInstanceReaper(services.Provider, interval)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reaper.Run(ctx)
+	}()
+
+	if k8sService, ok := services.Provider.(*services.KubernetesService); ok {
+		workers.Add(2)
+		go func() {
+			defer workers.Done()
+			k8sService.WatchInstanceStatus(ctx)
+		}()
+		go func() {
+			defer workers.Done()
+			k8sService.RunReconciler(ctx, reconcileInterval)
+		}()
+	}
+}
+
+// durationFromEnv lit une durée depuis une variable d'environnement
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Invalid %s %q, using %s", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// trustedProxies lit TRUSTED_PROXIES (IPs ou CIDR séparés par des virgules). Vide
+// par défaut : aucun proxy n'est cru.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
 
 type User struct {
 	ID           int       `json:"id"`
@@ -16,6 +20,8 @@ type User struct {
 // models/challenge.go
 type Challenge struct {
 	ID          int    `json:"id"`
+	Slug        string `json:"slug,omitempty"`     // Renseigné pour les challenges importés depuis un challenge.yml
+	EventID     *int   `json:"event_id,omitempty"` // Événement auquel le challenge est réservé
 	Name        string `json:"name_chall"`
 	Description string `json:"description"`
 	DockerImage string `json:"docker_image"`
@@ -24,7 +30,240 @@ type Challenge struct {
 	MemoryLimit string `json:"memory_limit"`
 	CreatedAt   string `json:"created_at"`
 	Category    string `json:"category"`
+	Difficulty  string `json:"difficulty,omitempty"`
 	Points      int    `json:"points"`
+	TimeLimit   int    `json:"time_limit"` // Durée de vie maximale d'une instance, en secondes
+	IsActive    bool   `json:"is_active"`
+	CreatedBy   int    `json:"created_by,omitempty"`
+	Flag        string `json:"-"`          // Ne jamais exposer le flag
+
+	// Score dynamique : Points est la valeur courante, recalculée à chaque résolution
+	ScoringType  string `json:"scoring_type"`
+	InitialValue int    `json:"initial_value,omitempty"`
+	MinimumValue int    `json:"minimum_value,omitempty"`
+	Decay        int    `json:"decay,omitempty"` // Nombre de résolutions pour atteindre la valeur minimale
+
+	Hints       []Hint       `json:"hints,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	Unlock *ChallengeUnlock `json:"unlock,omitempty"` // Conditions de déblocage, s'il y en a
+	Locked bool             `json:"locked,omitempty"` // Conditions non remplies par le joueur
+
+	// Fenêtre de publication : le challenge n'est visible qu'entre ces deux dates
+	VisibleFrom  *time.Time `json:"visible_from,omitempty"`
+	VisibleUntil *time.Time `json:"visible_until,omitempty"`
+}
+
+// Scope retourne l'événement hébergé du challenge, 0 pour la plateforme
+func (c *Challenge) Scope() int {
+	if c.EventID == nil {
+		return 0
+	}
+	return *c.EventID
+}
+
+// Attachment est un fichier fourni avec un challenge (binaire, pcap...)
+type Attachment struct {
+	ID          int       `json:"id"`
+	ChallengeID int       `json:"challenge_id"`
+	Filename    string    `json:"filename"`
+	StorageKey  string    `json:"-"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Hint est un indice d'un challenge, dont le contenu se débloque contre des points
+type Hint struct {
+	ID          int    `json:"id"`
+	ChallengeID int    `json:"challenge_id"`
+	Content     string `json:"content,omitempty"` // Visible uniquement une fois débloqué
+	Cost        int    `json:"cost"`
+	Unlocked    bool   `json:"unlocked"`
+}
+
+// Types de flags supplémentaires
+const (
+	FlagStatic = "static"
+	FlagRegex  = "regex"
+)
+
+// Flag est un flag accepté pour un challenge en plus de son flag principal (ou des
+// flags de ses instances). Un flag avec des points partiels les crédite une seule
+// fois sans résoudre le challenge ; sinon il résout le challenge.
+type Flag struct {
+	ID            int    `json:"id"`
+	ChallengeID   int    `json:"challenge_id"`
+	Type          string `json:"type"`
+	Content       string `json:"content"`
+	CaseSensitive bool   `json:"case_sensitive"`
+	Points        int    `json:"points"`
+}
+
+type CreateFlagRequest struct {
+	Type          string `json:"type" binding:"required,oneof=static regex"`
+	Content       string `json:"content" binding:"required,max=255"`
+	CaseSensitive *bool  `json:"case_sensitive"` // true par défaut
+	Points        int    `json:"points" binding:"min=0"`
+}
+
+type CreateHintRequest struct {
+	Content string `json:"content" binding:"required"`
+	Cost    int    `json:"cost" binding:"min=0"`
+}
+
+// Modes de calcul des points d'un challenge
+const (
+	ScoringStatic  = "static"
+	ScoringDynamic = "dynamic"
+)
+
+// Modes de déblocage d'un challenge par ses prérequis
+const (
+	UnlockAll = "all" // Tous les prérequis doivent être résolus
+	UnlockAny = "any" // Un seul prérequis suffit
+)
+
+// ChallengeUnlock décrit les conditions de déblocage d'un challenge : des
+// prérequis à résoudre (tous ou au moins un) et un score minimal dans son
+// événement
+type ChallengeUnlock struct {
+	Prerequisites []int  `json:"prerequisites"`
+	Mode          string `json:"mode"`
+	MinScore      int    `json:"min_score"`
+}
+
+// HasConditions indique si le challenge est soumis à au moins une condition
+func (u *ChallengeUnlock) HasConditions() bool {
+	return u != nil && (len(u.Prerequisites) > 0 || u.MinScore > 0)
+}
+
+type UpdatePrerequisitesRequest struct {
+	Prerequisites []int  `json:"prerequisites" binding:"dive,min=1"`
+	Mode          string `json:"mode" binding:"omitempty,oneof=all any"`
+	MinScore      int    `json:"min_score" binding:"min=0"`
+}
+
+// UpdateChallengeScheduleRequest remplace la fenêtre de publication d'un challenge ;
+// une date absente ou null supprime la borne correspondante
+type UpdateChallengeScheduleRequest struct {
+	VisibleFrom  *time.Time `json:"visible_from"`
+	VisibleUntil *time.Time `json:"visible_until"`
+}
+
+type UpdateChallengeScoringRequest struct {
+	ScoringType  string `json:"scoring_type" binding:"required,oneof=static dynamic"`
+	InitialValue int    `json:"initial_value" binding:"required,min=1"`
+	MinimumValue int    `json:"minimum_value" binding:"omitempty,min=1"`
+	Decay        int    `json:"decay" binding:"omitempty,min=1"`
+}
+
+// ChallengeSpec décrit un challenge dans un fichier challenge.yml. Le slug vaut par
+// défaut le nom du dossier du fichier ; les pièces jointes sont des chemins relatifs
+// à ce dossier. Le premier flag est le flag principal, les suivants sont des flags
+// supplémentaires.
+type ChallengeSpec struct {
+	Slug        string              `json:"slug,omitempty"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Category    string              `json:"category"`
+	Difficulty  string              `json:"difficulty"`
+	Points      int                 `json:"points"`
+	Event       string              `json:"event,omitempty"` // Slug de l'événement du challenge
+	Flags       []ChallengeSpecFlag `json:"flags"`
+	Image       string              `json:"image,omitempty"`
+	Port        int                 `json:"port,omitempty"`
+	Limits      ChallengeSpecLimits `json:"limits,omitempty"`
+	Hints       []ChallengeSpecHint `json:"hints,omitempty"`
+	Attachments []string            `json:"attachments,omitempty"`
+}
+
+type ChallengeSpecLimits struct {
+	CPU       string `json:"cpu,omitempty"`
+	Memory    string `json:"memory,omitempty"`
+	TimeLimit int    `json:"time_limit,omitempty"` // En secondes
+}
+
+// ChallengeSpecFlag est un flag de challenge.yml : une simple chaîne pour un flag
+// statique sensible à la casse, ou un objet pour les autres types
+type ChallengeSpecFlag struct {
+	Type          string `json:"type,omitempty"`
+	Content       string `json:"content"`
+	CaseSensitive *bool  `json:"case_sensitive,omitempty"`
+	Points        int    `json:"points,omitempty"`
+}
+
+func (f *ChallengeSpecFlag) UnmarshalJSON(data []byte) error {
+	var content string
+	if err := json.Unmarshal(data, &content); err == nil {
+		*f = ChallengeSpecFlag{Type: FlagStatic, Content: content}
+		return nil
+	}
+
+	type plain ChallengeSpecFlag
+	var flag plain
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&flag); err != nil {
+		return err
+	}
+	if flag.Type == "" {
+		flag.Type = FlagStatic
+	}
+	*f = ChallengeSpecFlag(flag)
+	return nil
+}
+
+// Flag convertit le flag en flag supplémentaire d'un challenge
+func (f ChallengeSpecFlag) Flag(challengeID int) Flag {
+	return Flag{
+		ChallengeID:   challengeID,
+		Type:          f.Type,
+		Content:       f.Content,
+		CaseSensitive: f.CaseSensitive == nil || *f.CaseSensitive,
+		Points:        f.Points,
+	}
+}
+
+type ChallengeSpecHint struct {
+	Content string `json:"content"`
+	Cost    int    `json:"cost"`
+}
+
+// Résultats possibles de l'import d'un challenge.yml
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportFailed    = "failed"
+)
+
+// ChallengeImportPlan regroupe les écritures d'un import de challenge.yml, appliquées
+// en base dans une seule transaction. Un challenge sans ID est créé.
+type ChallengeImportPlan struct {
+	Challenge        *Challenge
+	StaleFlags       []Flag
+	NewFlags         []Flag
+	StaleHints       []Hint
+	NewHints         []Hint
+	StaleAttachments []Attachment
+}
+
+// ChallengeImportResult rend compte de l'import d'un challenge.yml
+type ChallengeImportResult struct {
+	Slug        string                 `json:"slug"`
+	Path        string                 `json:"path"`
+	Action      string                 `json:"action"`
+	ChallengeID int                    `json:"challenge_id,omitempty"`
+	Changes     []ChallengeFieldChange `json:"changes,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// ChallengeFieldChange décrit la modification d'un champ lors d'un import
+type ChallengeFieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
 }
 
 
@@ -41,9 +280,11 @@ type Instance struct {
 	InternalPort int       `json:"internal_port,omitempty"`
 	AccessURL    string    `json:"access_url,omitempty"`
 	Status       string    `json:"status"`
+	StatusReason string    `json:"status_reason,omitempty"` // Ex: ImagePullBackOff, CrashLoopBackOff
 	StartedAt    time.Time `json:"started_at"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	LastAccessed time.Time `json:"last_accessed,omitempty"`
+	Flag         string    `json:"-"` // Flag unique à l'instance
 	Challenge    *Challenge `json:"challenge,omitempty"`
 }
 
@@ -52,23 +293,180 @@ type Submission struct {
 	UserID        int       `json:"user_id"`
 	ChallengeID   int       `json:"challenge_id"`
 	InstanceID    *int      `json:"instance_id,omitempty"`
+	TeamID        *int      `json:"team_id,omitempty"` // Équipe créditée en mode équipe
 	SubmittedFlag string    `json:"submitted_flag"`
 	IsValid       bool      `json:"is_valid"`
 	PointsAwarded int       `json:"points_awarded"`
+	FlagID        *int      `json:"flag_id,omitempty"` // Flag supplémentaire validé, nil pour le flag principal
+	SolveRank     int       `json:"solve_rank,omitempty"` // Rang de la résolution (1 = first blood)
+	Bonus         int       `json:"bonus,omitempty"`      // Bonus de rang crédité en plus des points
 	SubmittedAt   time.Time `json:"submitted_at"`
 	Challenge     *Challenge `json:"challenge,omitempty"`
 	User          *User     `json:"user,omitempty"`
 }
 
-type ChallengeSolve struct {
+// États d'un événement
+const (
+	EventScheduled = "scheduled"
+	EventRunning   = "running"
+	EventPaused    = "paused"
+	EventEnded     = "ended"
+)
+
+// Event est une compétition : les flags ne sont acceptés et les instances créées
+// qu'entre StartTime et EndTime, hors pause. À partir de FreezeTime, le classement
+// public n'évolue plus. Un événement sans slug s'applique à toute la plateforme ; un
+// événement avec un slug a ses propres challenges, inscriptions, classement et
+// namespace d'instances, sous /api/events/:slug.
+type Event struct {
+	ID         int        `json:"id"`
+	Slug       string     `json:"slug,omitempty"`
+	Name       string     `json:"name"`
+	Namespace  string     `json:"namespace,omitempty"`
+	StartTime  time.Time  `json:"start_time"`
+	EndTime    time.Time  `json:"end_time"`
+	FreezeTime *time.Time `json:"freeze_time,omitempty"`
+	PausedAt   *time.Time `json:"paused_at,omitempty"`
+	Status     string     `json:"status"`
+	Frozen     bool       `json:"frozen"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Mode de jeu propre à un événement hébergé ; vide ou nil : réglage de la plateforme
+	Mode           string `json:"mode,omitempty"`
+	ShareInstances *bool  `json:"share_instances,omitempty"`
+}
+
+type EventRequest struct {
+	Slug       string     `json:"slug"`
+	Name       string     `json:"name" binding:"required"`
+	Namespace  string     `json:"namespace"` // Par défaut "ctf-" suivi du slug
+	StartTime  time.Time  `json:"start_time" binding:"required"`
+	EndTime    time.Time  `json:"end_time" binding:"required,gtfield=StartTime"`
+	FreezeTime *time.Time `json:"freeze_time"`
+
+	// Réservés aux événements hébergés ; absents : réglages de la plateforme
+	Mode           string `json:"mode" binding:"omitempty,oneof=individual team"`
+	ShareInstances *bool  `json:"share_instances"`
+}
+
+// EventRegistration est l'inscription d'un joueur à un événement
+type EventRegistration struct {
+	EventID      int       `json:"event_id"`
+	UserID       int       `json:"user_id"`
+	Username     string    `json:"username"`
+	TeamID       *int      `json:"team_id,omitempty"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+type ExtendEventRequest struct {
+	Duration string `json:"duration" binding:"required"` // Ex : "30m", "2h"
+}
+
+// Types d'activités suspectes relevées lors des soumissions de flags
+const (
+	SuspiciousRateLimited     = "rate_limited"
+	SuspiciousLockout         = "lockout"
+	SuspiciousSharedWrongFlag = "shared_wrong_flag"
+)
+
+// SuspiciousActivity est une activité suspecte consignée pour les administrateurs
+type SuspiciousActivity struct {
 	ID          int       `json:"id"`
-	UserID      int       `json:"user_id"`
-	ChallengeID int       `json:"challenge_id"`
-	SolvedAt    time.Time `json:"solved_at"`
+	Type        string    `json:"type"`
+	UserID      *int      `json:"user_id,omitempty"`
+	Username    string    `json:"username,omitempty"`
+	ChallengeID *int      `json:"challenge_id,omitempty"`
+	IP          string    `json:"ip,omitempty"`
+	Details     string    `json:"details"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ChallengeSolve struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	Username      string    `json:"username,omitempty"`
+	TeamID        *int      `json:"team_id,omitempty"`
+	TeamName      string    `json:"team_name,omitempty"`
+	ChallengeID   int       `json:"challenge_id"`
+	ChallengeName string    `json:"challenge_name,omitempty"`
+	EventID       *int      `json:"event_id,omitempty"`
+	EventSlug     string    `json:"event_slug,omitempty"`
+	SolveRank     int       `json:"solve_rank"`
+	Bonus         int       `json:"bonus"`
+	SolvedAt      time.Time `json:"solved_at"`
 	User        *User     `json:"user,omitempty"`
 	Challenge   *Challenge `json:"challenge,omitempty"`
 }
 
+// FlagReview signale la soumission d'un flag appartenant à l'instance d'un autre joueur
+type FlagReview struct {
+	ID           int       `json:"id"`
+	SubmissionID int       `json:"submission_id"`
+	UserID       int       `json:"user_id"`
+	Username     string    `json:"username"`
+	ChallengeID  int       `json:"challenge_id"`
+	InstanceID   int       `json:"instance_id"`
+	OwnerID      int       `json:"owner_id"`
+	OwnerName    string    `json:"owner_name"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Modes de jeu d'un événement
+const (
+	ModeIndividual = "individual"
+	ModeTeam       = "team"
+)
+
+// Affichage des challenges dont les prérequis ne sont pas remplis
+const (
+	LockedShown  = "locked" // Listés avec locked: true, sans description
+	LockedHidden = "hidden" // Absents des listes, introuvables par ID
+)
+
+// EventSettings regroupe les réglages de la plateforme, dont les événements hébergés
+// héritent sauf mode de jeu et partage d'instances propres
+type EventSettings struct {
+	Mode             string `json:"mode"`              // individual ou team
+	ShareInstances   bool   `json:"share_instances"`   // En mode équipe, une instance par équipe et par challenge
+	BloodBonuses     []int  `json:"blood_bonuses"`     // Bonus des 1ère, 2e et 3e résolutions d'un challenge
+	LockedChallenges string `json:"locked_challenges"` // locked ou hidden
+}
+
+// Types d'annonces
+const (
+	AnnouncementFirstBlood        = "first_blood"
+	AnnouncementChallengeReleased = "challenge_released"
+)
+
+// Announcement est un événement diffusé aux joueurs (first blood, nouveau challenge...)
+type Announcement struct {
+	ID          int       `json:"id"`
+	Type        string    `json:"type"`
+	Message     string    `json:"message"`
+	ChallengeID *int      `json:"challenge_id,omitempty"`
+	UserID      *int      `json:"user_id,omitempty"`
+	TeamID      *int      `json:"team_id,omitempty"`
+	EventID     *int      `json:"event_id,omitempty"` // Événement hébergé du challenge concerné
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Team struct {
+	ID         int          `json:"id"`
+	Name       string       `json:"name"`
+	InviteCode string       `json:"invite_code,omitempty"` // Visible uniquement par les membres
+	CaptainID  int          `json:"captain_id"`
+	Score      int          `json:"score"`
+	CreatedAt  time.Time    `json:"created_at"`
+	Members    []TeamMember `json:"members,omitempty"`
+}
+
+type TeamMember struct {
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Score     int    `json:"score"` // Points rapportés par le membre
+	IsCaptain bool   `json:"is_captain"`
+}
+
 type UserSession struct {
 	ID           int       `json:"id"`
 	UserID       int       `json:"user_id"`
@@ -94,7 +492,20 @@ type CreateInstanceRequest struct {
 }
 
 type SubmitFlagRequest struct {
-	Flag string `json:"flag" binding:"required"`
+	ChallengeID int    `json:"challenge_id" binding:"required"`
+	Flag        string `json:"flag" binding:"required"`
+}
+
+type CreateTeamRequest struct {
+	Name string `json:"name" binding:"required,min=3,max=50"`
+}
+
+type JoinTeamRequest struct {
+	InviteCode string `json:"invite_code" binding:"required"`
+}
+
+type TransferCaptainRequest struct {
+	UserID int `json:"user_id" binding:"required"`
 }
 
 type CreateChallengeRequest struct {
@@ -104,11 +515,15 @@ type CreateChallengeRequest struct {
 	Difficulty  string `json:"difficulty" binding:"required,oneof=easy medium hard"`
 	Points      int    `json:"points" binding:"required,min=1"`
 	Flag        string `json:"flag" binding:"required"`
-	DockerImage string `json:"docker_image" binding:"required"`
-	Port        int    `json:"port" binding:"required"`
+	DockerImage string `json:"docker_image"` // Facultatif pour les catégories reverse et forensics
+	Port        int    `json:"port"`
 	CPULimit    string `json:"cpu_limit"`
 	MemoryLimit string `json:"memory_limit"`
 	TimeLimit   int    `json:"time_limit"`
+	EventID     *int   `json:"event_id"`
+
+	VisibleFrom  *time.Time `json:"visible_from"`
+	VisibleUntil *time.Time `json:"visible_until"`
 }
 
 type UpdateChallengeRequest struct {
@@ -124,13 +539,53 @@ type UpdateChallengeRequest struct {
 	MemoryLimit string `json:"memory_limit"`
 	TimeLimit   int    `json:"time_limit"`
 	IsActive    *bool  `json:"is_active"`
+	EventID     *int   `json:"event_id"` // 0 détache le challenge de son événement
 }
 
 type LeaderboardEntry struct {
-	Rank     int    `json:"rank"`
-	Username string `json:"username"`
-	Score    int    `json:"score"`
-	Solves   int    `json:"solves"`
+	Rank        int        `json:"rank"`
+	UserID      int        `json:"user_id"`
+	Username    string     `json:"username"`
+	Score       int        `json:"score"`
+	Solves      int        `json:"solves"`
+	LastSolveAt *time.Time `json:"last_solve_at,omitempty"`
+}
+
+type TeamLeaderboardEntry struct {
+	Rank        int        `json:"rank"`
+	TeamID      int        `json:"team_id"`
+	Name        string     `json:"name"`
+	Score       int        `json:"score"`
+	Solves      int        `json:"solves"`
+	Members     int        `json:"members"`
+	LastSolveAt *time.Time `json:"last_solve_at,omitempty"`
+}
+
+// CTFtimeStanding est une ligne du classement au format d'import de CTFtime ; team
+// est le nom du joueur en mode individuel
+type CTFtimeStanding struct {
+	Pos   int    `json:"pos"`
+	Team  string `json:"team"`
+	Score int    `json:"score"`
+}
+
+// CTFtimeScoreboard est le classement final au format d'import de CTFtime
+type CTFtimeScoreboard struct {
+	Standings []CTFtimeStanding `json:"standings"`
+}
+
+// ScorePoint est le score cumulé d'un joueur après une résolution
+type ScorePoint struct {
+	Time  time.Time `json:"time"`
+	Score int       `json:"score"`
+}
+
+// ScoreSeries est l'évolution du score d'un joueur dans le temps
+type ScoreSeries struct {
+	UserID   int          `json:"user_id"`
+	Username string       `json:"username"`
+	Score    int          `json:"score"`
+	Points   []ScorePoint `json:"points"`
 }
 
 type PlatformStats struct {
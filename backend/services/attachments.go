@@ -0,0 +1,109 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// AttachmentKey construit la clé de stockage d'une pièce jointe : un préfixe aléatoire
+// évite les collisions entre deux fichiers de même nom
+func AttachmentKey(challengeID int, filename string) (string, error) {
+	prefix := make([]byte, 8)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", err
+	}
+
+	name := unsafeFilenameChars.ReplaceAllString(filepath.Base(filename), "_")
+	return fmt.Sprintf("%d/%s-%s", challengeID, hex.EncodeToString(prefix), name), nil
+}
+
+// ErrAttachmentSecretMissing signale l'absence d'ATTACHMENT_SECRET : sans secret, les
+// URLs de téléchargement pourraient être forgées par n'importe qui
+var ErrAttachmentSecretMissing = errors.New("ATTACHMENT_SECRET is not set")
+
+// SignAttachmentURL retourne l'URL de téléchargement d'une pièce jointe pour un
+// utilisateur, signée et valable pendant ATTACHMENT_URL_TTL (par défaut 15m)
+func SignAttachmentURL(attachmentID, userID int) (string, time.Time, error) {
+	expiresAt := time.Now().Add(attachmentURLTTL())
+	expires := expiresAt.Unix()
+	signature, err := attachmentSignature(attachmentID, userID, expires)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return fmt.Sprintf("/attachments/%d/download?uid=%d&expires=%d&sig=%s",
+		attachmentID, userID, expires, signature), expiresAt, nil
+}
+
+// VerifyAttachmentSignature vérifie la signature et l'expiration d'une URL de téléchargement
+func VerifyAttachmentSignature(attachmentID, userID int, expires int64, signature string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+	expected, err := attachmentSignature(attachmentID, userID, expires)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// CheckAttachmentSecret vérifie au démarrage que ATTACHMENT_SECRET est défini
+func CheckAttachmentSecret() error {
+	_, err := attachmentSecret()
+	return err
+}
+
+func attachmentSignature(attachmentID, userID int, expires int64) (string, error) {
+	secret, err := attachmentSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d:%d:%d", attachmentID, userID, expires)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// attachmentSecret retourne le secret serveur utilisé pour signer les URLs de
+// téléchargement. Il est propre aux pièces jointes : JWT_SECRET n'est pas réutilisé.
+func attachmentSecret() ([]byte, error) {
+	secret := os.Getenv("ATTACHMENT_SECRET")
+	if secret == "" {
+		return nil, ErrAttachmentSecretMissing
+	}
+	return []byte(secret), nil
+}
+
+func attachmentURLTTL() time.Duration {
+	value := os.Getenv("ATTACHMENT_URL_TTL")
+	if value == "" {
+		return 15 * time.Minute
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Printf("Invalid ATTACHMENT_URL_TTL %q, using 15m", value)
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+// AttachmentMaxSize retourne la taille maximale d'une pièce jointe en octets,
+// configurable en Mo avec ATTACHMENT_MAX_MB (par défaut 100)
+func AttachmentMaxSize() int64 {
+	megabytes, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_MB"), 10, 64)
+	if err != nil || megabytes <= 0 {
+		megabytes = 100
+	}
+	return megabytes << 20
+}
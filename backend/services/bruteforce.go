@@ -0,0 +1,112 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// FlagLockout retourne le temps restant avant qu'un utilisateur puisse de nouveau
+// soumettre un flag pour un challenge, ou 0 s'il n'est pas bloqué. Un utilisateur est
+// bloqué après FLAG_LOCKOUT_ATTEMPTS (par défaut 10, 0 pour désactiver) flags
+// incorrects en FLAG_LOCKOUT_WINDOW (par défaut 10m), jusqu'à ce que le plus ancien
+// sorte de la fenêtre.
+func FlagLockout(userID, challengeID int) (time.Duration, error) {
+	attempts, window := lockoutSettings()
+	if attempts == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	times, err := db.GetRecentWrongSubmissionTimes(userID, challengeID, now.Add(-window), attempts)
+	if err != nil || len(times) < attempts {
+		return 0, err
+	}
+
+	remaining := times[len(times)-1].Add(window).Sub(now)
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	return remaining, nil
+}
+
+// ReportWrongFlag consigne les activités suspectes révélées par un flag incorrect :
+// le blocage de l'utilisateur, ou le même flag incorrect soumis par
+// FLAG_SHARED_WRONG_USERS (par défaut 3) utilisateurs, signe d'un flag qui circule
+func ReportWrongFlag(submission *models.Submission, ip string) {
+	if attempts, window := lockoutSettings(); attempts > 0 {
+		times, err := db.GetRecentWrongSubmissionTimes(submission.UserID, submission.ChallengeID, time.Now().Add(-window), attempts+1)
+		if err != nil {
+			log.Printf("Error counting wrong submissions of user %d: %v", submission.UserID, err)
+		} else if len(times) == attempts {
+			ReportSuspiciousActivity(&models.SuspiciousActivity{
+				Type:        models.SuspiciousLockout,
+				UserID:      &submission.UserID,
+				ChallengeID: &submission.ChallengeID,
+				IP:          ip,
+				Details:     fmt.Sprintf("%d wrong flags in %s", attempts, window),
+			})
+		}
+	}
+
+	threshold := intFromEnv("FLAG_SHARED_WRONG_USERS", 3)
+	if threshold == 0 {
+		return
+	}
+	users, err := db.CountUsersWithWrongFlag(submission.ChallengeID, submission.SubmittedFlag)
+	if err != nil {
+		log.Printf("Error counting users with wrong flag on challenge %d: %v", submission.ChallengeID, err)
+		return
+	}
+	// Consigné une seule fois, lorsque le seuil est atteint
+	if users == threshold {
+		ReportSuspiciousActivity(&models.SuspiciousActivity{
+			Type:        models.SuspiciousSharedWrongFlag,
+			UserID:      &submission.UserID,
+			ChallengeID: &submission.ChallengeID,
+			IP:          ip,
+			Details:     fmt.Sprintf("%d users submitted the same wrong flag %q", users, submission.SubmittedFlag),
+		})
+	}
+}
+
+// ReportSuspiciousActivity enregistre une activité suspecte dans le journal des administrateurs
+func ReportSuspiciousActivity(activity *models.SuspiciousActivity) {
+	if err := db.CreateSuspiciousActivity(activity); err != nil {
+		log.Printf("Error recording suspicious activity %s: %v", activity.Type, err)
+		return
+	}
+	log.Printf("Suspicious activity (%s): %s", activity.Type, activity.Details)
+}
+
+func lockoutSettings() (int, time.Duration) {
+	attempts := intFromEnv("FLAG_LOCKOUT_ATTEMPTS", 10)
+	window := 10 * time.Minute
+	if value := os.Getenv("FLAG_LOCKOUT_WINDOW"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			window = parsed
+		} else {
+			log.Printf("Invalid FLAG_LOCKOUT_WINDOW %q, using %s", value, window)
+		}
+	}
+	return attempts, window
+}
+
+// intFromEnv lit un entier positif ou nul depuis une variable d'environnement
+func intFromEnv(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Printf("Invalid %s %q, using %d", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"fmt"
+	"testing"
+)
+
+func submitWrongFlag(t *testing.T, userID int, flag string) {
+	t.Helper()
+	submission := &models.Submission{UserID: userID, ChallengeID: 1, SubmittedFlag: flag}
+	if _, err := db.RecordSubmission(submission); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+	ReportWrongFlag(submission, "203.0.113.7")
+}
+
+func activityTypes(t *testing.T) map[string]int {
+	t.Helper()
+	activities, err := db.GetSuspiciousActivities(100)
+	if err != nil {
+		t.Fatalf("GetSuspiciousActivities: %v", err)
+	}
+	types := make(map[string]int)
+	for _, activity := range activities {
+		types[activity.Type]++
+	}
+	return types
+}
+
+func TestFlagLockoutAfterWrongAttempts(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("FLAG_LOCKOUT_ATTEMPTS", "3")
+	t.Setenv("FLAG_LOCKOUT_WINDOW", "10m")
+
+	for i := 0; i < 3; i++ {
+		if lockout, err := FlagLockout(1, 1); err != nil || lockout != 0 {
+			t.Fatalf("attempt %d: lockout = %s, err = %v", i+1, lockout, err)
+		}
+		submitWrongFlag(t, 1, fmt.Sprintf("CTF{guess_%d}", i))
+	}
+
+	lockout, err := FlagLockout(1, 1)
+	if err != nil {
+		t.Fatalf("FlagLockout: %v", err)
+	}
+	if lockout <= 0 {
+		t.Error("user not locked out after 3 wrong flags")
+	}
+	if lockout, _ := FlagLockout(2, 1); lockout != 0 {
+		t.Error("lockout applied to another user")
+	}
+	if got := activityTypes(t)[models.SuspiciousLockout]; got != 1 {
+		t.Errorf("%d lockout events recorded, want 1", got)
+	}
+}
+
+func TestSharedWrongFlagIsReportedOnce(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("FLAG_SHARED_WRONG_USERS", "2")
+
+	for _, userID := range []int{1, 2, 3} {
+		submitWrongFlag(t, userID, "CTF{leaked_decoy}")
+	}
+
+	if got := activityTypes(t)[models.SuspiciousSharedWrongFlag]; got != 1 {
+		t.Errorf("%d shared wrong flag events recorded, want 1", got)
+	}
+}
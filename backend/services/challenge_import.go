@@ -0,0 +1,503 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ChallengesDir retourne le dossier des challenges à importer, CHALLENGES_DIR
+// (par défaut ../img_docker_chall)
+func ChallengesDir() string {
+	if dir := os.Getenv("CHALLENGES_DIR"); dir != "" {
+		return dir
+	}
+	return "../img_docker_chall"
+}
+
+// LoadChallengeSpec lit un fichier challenge.yml. Les champs inconnus sont refusés
+// pour détecter les fautes de frappe.
+func LoadChallengeSpec(path string) (*models.ChallengeSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec models.ChallengeSpec
+	if err := yaml.UnmarshalStrict(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", filepath.Base(path), err)
+	}
+
+	if spec.Slug == "" {
+		spec.Slug = strings.ToLower(filepath.Base(filepath.Dir(path)))
+	}
+	if !slugPattern.MatchString(spec.Slug) {
+		return nil, fmt.Errorf("invalid slug %q (lowercase letters, digits, '-' and '_')", spec.Slug)
+	}
+	if len(spec.Flags) == 0 {
+		return nil, fmt.Errorf("at least one flag is required")
+	}
+	if primary := spec.Flags[0]; primary.Type != models.FlagStatic || primary.CaseSensitive != nil || primary.Points != 0 {
+		return nil, fmt.Errorf("the first flag must be a plain case-sensitive static flag")
+	}
+	for _, specFlag := range spec.Flags[1:] {
+		flag := specFlag.Flag(0)
+		if err := ValidateFlag(&flag); err != nil {
+			return nil, fmt.Errorf("invalid flag: %v", err)
+		}
+	}
+	for _, hint := range spec.Hints {
+		if strings.TrimSpace(hint.Content) == "" || hint.Cost < 0 {
+			return nil, fmt.Errorf("hints need a content and a non-negative cost")
+		}
+	}
+	return &spec, nil
+}
+
+// specAttachment est un fichier local référencé par un challenge.yml
+type specAttachment struct {
+	filename string
+	path     string
+	sha256   string
+}
+
+// ImportChallengeDir parcourt root à la recherche de fichiers challenge.yml et crée
+// ou met à jour les challenges correspondants, identifiés par leur slug. Un challenge
+// sans slug portant le même nom est rattaché à son fichier plutôt que dupliqué.
+// Les indices et pièces jointes sont alignés sur le fichier. Avec dryRun, rien n'est
+// modifié et seules les différences sont retournées.
+func ImportChallengeDir(root string, dryRun bool) ([]models.ChallengeImportResult, error) {
+	results := []models.ChallengeImportResult{}
+	slugs := make(map[string]string)
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || (entry.Name() != "challenge.yml" && entry.Name() != "challenge.yaml") {
+			return nil
+		}
+
+		result := importChallengeSpec(path, dryRun, slugs)
+		if result.Action == models.ImportFailed {
+			log.Printf("Import of %s failed: %s", path, result.Error)
+		} else if !dryRun && result.Action != models.ImportUnchanged {
+			log.Printf("Challenge %s %s from %s (%d changes)", result.Slug, result.Action, path, len(result.Changes))
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+func importChallengeSpec(path string, dryRun bool, slugs map[string]string) models.ChallengeImportResult {
+	result := models.ChallengeImportResult{Path: path}
+	fail := func(err error) models.ChallengeImportResult {
+		result.Action = models.ImportFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	spec, err := LoadChallengeSpec(path)
+	if err != nil {
+		return fail(err)
+	}
+	result.Slug = spec.Slug
+	if other, exists := slugs[spec.Slug]; exists {
+		return fail(fmt.Errorf("slug %q already used by %s", spec.Slug, other))
+	}
+	slugs[spec.Slug] = path
+
+	challenge := &models.Challenge{
+		Slug:        spec.Slug,
+		Name:        spec.Name,
+		Description: spec.Description,
+		Category:    spec.Category,
+		Difficulty:  spec.Difficulty,
+		Points:      spec.Points,
+		Flag:        strings.TrimSpace(spec.Flags[0].Content),
+		DockerImage: spec.Image,
+		Port:        spec.Port,
+		CPULimit:    spec.Limits.CPU,
+		MemoryLimit: spec.Limits.Memory,
+		TimeLimit:   spec.Limits.TimeLimit,
+		ScoringType: models.ScoringStatic,
+		IsActive:    true,
+	}
+	if challenge.DockerImage == "" && !IsFileCategory(challenge.Category) {
+		return fail(fmt.Errorf("image is required for category %q", challenge.Category))
+	}
+	ApplyChallengeDefaults(challenge)
+
+	if spec.Event != "" {
+		event, err := db.GetEventBySlug(spec.Event)
+		if err != nil {
+			return fail(err)
+		}
+		if event == nil {
+			return fail(fmt.Errorf("unknown event %q", spec.Event))
+		}
+		challenge.EventID = &event.ID
+	}
+
+	files, err := loadSpecAttachments(filepath.Dir(path), spec.Attachments)
+	if err != nil {
+		return fail(err)
+	}
+
+	existing, err := db.GetChallengeBySlug(spec.Slug)
+	if err == nil && existing == nil {
+		existing, err = db.GetUnsluggedChallengeByName(spec.Name)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	var flags []models.Flag
+	var hints []models.Hint
+	var attachments []models.Attachment
+	if existing == nil {
+		result.Action = models.ImportCreated
+	} else {
+		result.ChallengeID = existing.ID
+		challenge.ID = existing.ID
+		challenge.IsActive = existing.IsActive
+		challenge.ScoringType = existing.ScoringType
+		challenge.MinimumValue = existing.MinimumValue
+		challenge.Decay = existing.Decay
+		// Pour un challenge dynamique, points désigne la valeur initiale
+		if existing.ScoringType == models.ScoringDynamic {
+			challenge.InitialValue = spec.Points
+			challenge.Points = existing.Points
+			existing.Points = existing.InitialValue
+		}
+
+		if flags, err = db.GetChallengeFlags(existing.ID); err != nil {
+			return fail(err)
+		}
+		if hints, err = db.GetChallengeHintContents(existing.ID); err != nil {
+			return fail(err)
+		}
+		if attachments, err = db.GetChallengeAttachments(existing.ID); err != nil {
+			return fail(err)
+		}
+	}
+	if err := ValidateChallenge(challenge); err != nil {
+		return fail(err)
+	}
+	if existing != nil {
+		result.Changes = diffChallenge(existing, challenge, spec.Points)
+	}
+
+	staleFlags, newFlags := planFlags(flags, spec.Flags[1:])
+	staleHints, newHints := planHints(hints, spec.Hints)
+	staleAttachments, newAttachments := planAttachments(attachments, files)
+	if (len(staleAttachments) > 0 || len(newAttachments) > 0) && Storage == nil {
+		return fail(fmt.Errorf("attachment storage not initialized"))
+	}
+	for _, flag := range staleFlags {
+		result.Changes = append(result.Changes, models.ChallengeFieldChange{Field: "extra_flag", From: describeFlag(flag)})
+	}
+	for _, flag := range newFlags {
+		result.Changes = append(result.Changes, models.ChallengeFieldChange{Field: "extra_flag", To: describeFlag(flag)})
+	}
+	for _, hint := range staleHints {
+		result.Changes = append(result.Changes, models.ChallengeFieldChange{Field: "hint", From: describeHint(hint.Content, hint.Cost)})
+	}
+	for _, hint := range newHints {
+		result.Changes = append(result.Changes, models.ChallengeFieldChange{Field: "hint", To: describeHint(hint.Content, hint.Cost)})
+	}
+	for _, attachment := range staleAttachments {
+		result.Changes = append(result.Changes, models.ChallengeFieldChange{Field: "attachment", From: describeAttachment(attachment.Filename, attachment.SHA256)})
+	}
+	for _, file := range newAttachments {
+		result.Changes = append(result.Changes, models.ChallengeFieldChange{Field: "attachment", To: describeAttachment(file.filename, file.sha256)})
+	}
+
+	if existing != nil {
+		if len(result.Changes) == 0 {
+			result.Action = models.ImportUnchanged
+			return result
+		}
+		result.Action = models.ImportUpdated
+	}
+	if dryRun {
+		return result
+	}
+
+	plan := &models.ChallengeImportPlan{
+		Challenge:        challenge,
+		StaleFlags:       staleFlags,
+		NewFlags:         newFlags,
+		StaleHints:       staleHints,
+		StaleAttachments: staleAttachments,
+	}
+	for _, hint := range newHints {
+		plan.NewHints = append(plan.NewHints, models.Hint{Content: hint.Content, Cost: hint.Cost})
+	}
+
+	// Les nouveaux fichiers sont stockés pendant la transaction, et supprimés si elle
+	// échoue ; les anciens ne le sont qu'une fois l'import validé
+	var storedKeys []string
+	storeFiles := func(challengeID int) ([]models.Attachment, error) {
+		stored := make([]models.Attachment, 0, len(newAttachments))
+		for _, file := range newAttachments {
+			attachment, err := storeSpecAttachment(challengeID, file)
+			if err != nil {
+				return nil, err
+			}
+			storedKeys = append(storedKeys, attachment.StorageKey)
+			stored = append(stored, *attachment)
+		}
+		return stored, nil
+	}
+	if err := db.ImportChallenge(plan, storeFiles); err != nil {
+		for _, key := range storedKeys {
+			if deleteErr := Storage.Delete(key); deleteErr != nil {
+				log.Printf("Failed to delete stored file %s: %v", key, deleteErr)
+			}
+		}
+		return fail(err)
+	}
+	result.ChallengeID = challenge.ID
+
+	for _, attachment := range staleAttachments {
+		if err := Storage.Delete(attachment.StorageKey); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", attachment.StorageKey, err)
+		}
+	}
+	return result
+}
+
+// loadSpecAttachments vérifie et calcule l'empreinte des pièces jointes d'un
+// challenge.yml, qui doivent se trouver dans le dossier du fichier
+func loadSpecAttachments(dir string, paths []string) ([]specAttachment, error) {
+	files := make([]specAttachment, 0, len(paths))
+	for _, rel := range paths {
+		if !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("attachment %q must be inside the challenge directory", rel)
+		}
+
+		path := filepath.Join(dir, rel)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("attachment %q is not a regular file", rel)
+		}
+		if info.Size() > AttachmentMaxSize() {
+			return nil, fmt.Errorf("attachment %q exceeds %d MB", rel, AttachmentMaxSize()>>20)
+		}
+
+		checksum, err := fileSHA256(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, specAttachment{filename: filepath.Base(rel), path: path, sha256: checksum})
+	}
+	return files, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// storeSpecAttachment écrit une pièce jointe d'un challenge.yml dans le stockage et
+// retourne la pièce jointe à enregistrer en base
+func storeSpecAttachment(challengeID int, file specAttachment) (*models.Attachment, error) {
+	source, err := os.Open(file.path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	key, err := AttachmentKey(challengeID, file.filename)
+	if err != nil {
+		return nil, err
+	}
+	size, checksum, err := Storage.Save(key, source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Attachment{
+		ChallengeID: challengeID,
+		Filename:    file.filename,
+		StorageKey:  key,
+		Size:        size,
+		SHA256:      checksum,
+	}, nil
+}
+
+// diffChallenge liste les champs modifiés par un import. La valeur des flags n'est
+// jamais rapportée.
+func diffChallenge(current, updated *models.Challenge, points int) []models.ChallengeFieldChange {
+	changes := []models.ChallengeFieldChange{}
+	compare := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, models.ChallengeFieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	compare("slug", current.Slug, updated.Slug)
+	compare("name", current.Name, updated.Name)
+	compare("description", current.Description, updated.Description)
+	compare("category", current.Category, updated.Category)
+	compare("difficulty", current.Difficulty, updated.Difficulty)
+	compare("points", strconv.Itoa(current.Points), strconv.Itoa(points))
+	compare("image", current.DockerImage, updated.DockerImage)
+	compare("port", strconv.Itoa(current.Port), strconv.Itoa(updated.Port))
+	compare("cpu_limit", current.CPULimit, updated.CPULimit)
+	compare("memory_limit", current.MemoryLimit, updated.MemoryLimit)
+	compare("time_limit", strconv.Itoa(current.TimeLimit), strconv.Itoa(updated.TimeLimit))
+	compare("event", describeEvent(current.EventID), describeEvent(updated.EventID))
+	if current.Flag != updated.Flag {
+		changes = append(changes, models.ChallengeFieldChange{Field: "flag"})
+	}
+	return changes
+}
+
+// describeEvent désigne l'événement d'un challenge par son slug
+func describeEvent(eventID *int) string {
+	if eventID == nil {
+		return ""
+	}
+	event, err := db.GetEventByID(*eventID)
+	if err != nil || event == nil {
+		return "#" + strconv.Itoa(*eventID)
+	}
+	return event.Slug
+}
+
+// planFlags retourne les flags supplémentaires existants absents du fichier et ceux
+// à créer. Un flag est identifié par son type, son contenu, sa casse et ses points.
+func planFlags(current []models.Flag, wanted []models.ChallengeSpecFlag) ([]models.Flag, []models.Flag) {
+	remaining := make(map[models.Flag]int)
+	for _, specFlag := range wanted {
+		remaining[specFlag.Flag(0)]++
+	}
+
+	stale := []models.Flag{}
+	for _, flag := range current {
+		key := flag
+		key.ID, key.ChallengeID = 0, 0
+		if remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		stale = append(stale, flag)
+	}
+
+	added := []models.Flag{}
+	for _, specFlag := range wanted {
+		flag := specFlag.Flag(0)
+		if remaining[flag] > 0 {
+			remaining[flag]--
+			added = append(added, flag)
+		}
+	}
+	return stale, added
+}
+
+// planHints retourne les indices existants absents du fichier et ceux à créer. Un
+// indice est identifié par son contenu et son coût.
+func planHints(current []models.Hint, wanted []models.ChallengeSpecHint) ([]models.Hint, []models.ChallengeSpecHint) {
+	remaining := make(map[string]int)
+	for _, hint := range wanted {
+		remaining[describeHint(hint.Content, hint.Cost)]++
+	}
+
+	stale := []models.Hint{}
+	for _, hint := range current {
+		key := describeHint(hint.Content, hint.Cost)
+		if remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		stale = append(stale, hint)
+	}
+
+	added := []models.ChallengeSpecHint{}
+	for _, hint := range wanted {
+		key := describeHint(hint.Content, hint.Cost)
+		if remaining[key] > 0 {
+			remaining[key]--
+			added = append(added, hint)
+		}
+	}
+	return stale, added
+}
+
+// planAttachments retourne les pièces jointes existantes absentes du fichier et
+// celles à ajouter. Une pièce jointe est identifiée par son nom et son empreinte.
+func planAttachments(current []models.Attachment, wanted []specAttachment) ([]models.Attachment, []specAttachment) {
+	remaining := make(map[string]int)
+	for _, file := range wanted {
+		remaining[describeAttachment(file.filename, file.sha256)]++
+	}
+
+	stale := []models.Attachment{}
+	for _, attachment := range current {
+		key := describeAttachment(attachment.Filename, attachment.SHA256)
+		if remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		stale = append(stale, attachment)
+	}
+
+	added := []specAttachment{}
+	for _, file := range wanted {
+		key := describeAttachment(file.filename, file.sha256)
+		if remaining[key] > 0 {
+			remaining[key]--
+			added = append(added, file)
+		}
+	}
+	return stale, added
+}
+
+// describeFlag décrit un flag supplémentaire sans révéler son contenu
+func describeFlag(flag models.Flag) string {
+	description := flag.Type
+	if !flag.CaseSensitive {
+		description += ", case-insensitive"
+	}
+	if flag.Points > 0 {
+		description += fmt.Sprintf(", %d partial points", flag.Points)
+	}
+	return description
+}
+
+func describeHint(content string, cost int) string {
+	return fmt.Sprintf("%s (cost %d)", content, cost)
+}
+
+func describeAttachment(filename, checksum string) string {
+	return fmt.Sprintf("%s (sha256 %s)", filename, checksum)
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const crackmeSpec = `name: Crackme
+description: Retrouvez le mot de passe.
+category: Reverse
+difficulty: Medium
+points: 200
+flags:
+  - CTF{crackme_done}
+  - type: regex
+    content: 'CTF\{stage_[0-9]\}'
+    case_sensitive: false
+    points: 50
+hints:
+  - content: Regardez strcmp
+    cost: 20
+attachments:
+  - crackme.bin
+`
+
+func setupTestStorage(t *testing.T) {
+	t.Helper()
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	previous := Storage
+	Storage = storage
+	t.Cleanup(func() { Storage = previous })
+}
+
+func writeSpecDir(t *testing.T, root, slug, spec string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, slug)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	files["challenge.yml"] = spec
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile(%s): %v", name, err)
+		}
+	}
+}
+
+func importOne(t *testing.T, root string, dryRun bool) models.ChallengeImportResult {
+	t.Helper()
+	results, err := ImportChallengeDir(root, dryRun)
+	if err != nil {
+		t.Fatalf("ImportChallengeDir: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	return results[0]
+}
+
+func TestImportChallengeDirIsIdempotent(t *testing.T) {
+	setupTestDB(t)
+	setupTestStorage(t)
+	root := t.TempDir()
+	writeSpecDir(t, root, "crackme", crackmeSpec, map[string]string{"crackme.bin": "ELF v1"})
+
+	if result := importOne(t, root, true); result.Action != models.ImportCreated {
+		t.Fatalf("dry run action = %q (%s), want created", result.Action, result.Error)
+	}
+	if challenge, _ := db.GetChallengeBySlug("crackme"); challenge != nil {
+		t.Fatal("dry run created the challenge")
+	}
+
+	created := importOne(t, root, false)
+	if created.Action != models.ImportCreated {
+		t.Fatalf("action = %q (%s), want created", created.Action, created.Error)
+	}
+	challenge, err := db.GetChallengeBySlug("crackme")
+	if err != nil || challenge == nil {
+		t.Fatalf("GetChallengeBySlug: %v", err)
+	}
+	if challenge.ID != created.ChallengeID || challenge.Points != 200 || challenge.DockerImage != "" {
+		t.Errorf("imported challenge = %+v", challenge)
+	}
+	flags, _ := db.GetChallengeFlags(challenge.ID)
+	hints, _ := db.GetChallengeHintContents(challenge.ID)
+	attachments, _ := db.GetChallengeAttachments(challenge.ID)
+	if len(flags) != 1 || flags[0].Type != models.FlagRegex || flags[0].CaseSensitive || flags[0].Points != 50 {
+		t.Errorf("flags = %+v", flags)
+	}
+	if len(hints) != 1 || len(attachments) != 1 || attachments[0].Filename != "crackme.bin" {
+		t.Fatalf("hints = %+v, attachments = %+v", hints, attachments)
+	}
+
+	if result := importOne(t, root, false); result.Action != models.ImportUnchanged {
+		t.Errorf("second import action = %q, changes = %+v", result.Action, result.Changes)
+	}
+}
+
+func TestImportChallengeDirReportsChanges(t *testing.T) {
+	setupTestDB(t)
+	setupTestStorage(t)
+	root := t.TempDir()
+	writeSpecDir(t, root, "crackme", crackmeSpec, map[string]string{"crackme.bin": "ELF v1"})
+	importOne(t, root, false)
+
+	updatedSpec := crackmeSpec[:len(crackmeSpec)-len("attachments:\n  - crackme.bin\n")]
+	writeSpecDir(t, root, "crackme", strings.Replace(updatedSpec, "points: 200", "points: 250", 1), map[string]string{})
+
+	result := importOne(t, root, false)
+	if result.Action != models.ImportUpdated {
+		t.Fatalf("action = %q (%s), want updated", result.Action, result.Error)
+	}
+	fields := make(map[string]bool)
+	for _, change := range result.Changes {
+		fields[change.Field] = true
+	}
+	if len(result.Changes) != 2 || !fields["points"] || !fields["attachment"] {
+		t.Errorf("changes = %+v, want points and attachment", result.Changes)
+	}
+
+	challenge, _ := db.GetChallengeBySlug("crackme")
+	attachments, _ := db.GetChallengeAttachments(challenge.ID)
+	if challenge.Points != 250 || len(attachments) != 0 {
+		t.Errorf("points = %d, attachments = %d after update", challenge.Points, len(attachments))
+	}
+}
+
+// brokenStorage refuse toute écriture
+type brokenStorage struct{ FileStorage }
+
+func (brokenStorage) Save(key string, content io.Reader) (int64, string, error) {
+	return 0, "", errors.New("disk full")
+}
+
+func TestImportChallengeDirIsAtomic(t *testing.T) {
+	setupTestDB(t)
+	setupTestStorage(t)
+	root := t.TempDir()
+	writeSpecDir(t, root, "crackme", crackmeSpec, map[string]string{"crackme.bin": "ELF v1"})
+	created := importOne(t, root, false)
+
+	// Nouveaux points et indice, et un fichier modifié que le stockage refuse
+	updatedSpec := strings.Replace(crackmeSpec, "points: 200", "points: 250", 1)
+	updatedSpec = strings.Replace(updatedSpec, "    cost: 20\n", "    cost: 20\n  - content: Regardez aussi strlen\n    cost: 10\n", 1)
+	writeSpecDir(t, root, "crackme", updatedSpec, map[string]string{"crackme.bin": "ELF v2"})
+	Storage = brokenStorage{Storage}
+
+	if result := importOne(t, root, false); result.Action != models.ImportFailed {
+		t.Fatalf("action = %q, want failed", result.Action)
+	}
+	challenge, err := db.GetChallengeByID(created.ChallengeID)
+	if err != nil || challenge == nil {
+		t.Fatalf("GetChallengeByID: %v", err)
+	}
+	hints, _ := db.GetChallengeHintContents(challenge.ID)
+	attachments, _ := db.GetChallengeAttachments(challenge.ID)
+	if challenge.Points != 200 || len(hints) != 1 || len(attachments) != 1 {
+		t.Errorf("after a failed import: points = %d, %d hints, %d attachments, want 200, 1 and 1",
+			challenge.Points, len(hints), len(attachments))
+	}
+}
+
+func TestImportChallengeDirAdoptsSeededChallenge(t *testing.T) {
+	setupTestDB(t)
+	root := t.TempDir()
+	writeSpecDir(t, root, "xss_reflected", `name: XSS Reflective
+description: Trouvez une faille XSS réfléchie dans ce mini site web.
+category: Web
+difficulty: Easy
+points: 75
+flags: ["CTF{xss_reflected_pwned}"]
+image: xss-vuln
+port: 80
+`, map[string]string{})
+
+	result := importOne(t, root, false)
+	if result.Action != models.ImportUpdated || result.ChallengeID != 1 {
+		t.Fatalf("result = %+v, want update of challenge 1", result)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Field != "slug" {
+		t.Errorf("changes = %+v, want only the slug", result.Changes)
+	}
+}
+
+func TestLoadChallengeSpecRejectsUnknownFields(t *testing.T) {
+	root := t.TempDir()
+	writeSpecDir(t, root, "typo", crackmeSpec+"pionts: 10\n", map[string]string{})
+	if _, err := LoadChallengeSpec(filepath.Join(root, "typo", "challenge.yml")); err == nil {
+		t.Error("LoadChallengeSpec accepted an unknown field")
+	}
+}
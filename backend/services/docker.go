@@ -0,0 +1,156 @@
+package services
+
+import (
+	"backend/models"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// DockerProvider exécute les challenges comme conteneurs locaux via la CLI docker.
+// Destiné au développement : aucun cluster Kubernetes n'est nécessaire.
+type DockerProvider struct {
+	binary string
+	host   string
+	ports  *PortAllocator
+}
+
+// NewDockerProvider vérifie que la CLI docker est disponible. Le binaire peut être
+// surchargé avec DOCKER_BIN (ex: podman) et l'hôte des URLs d'accès avec LOCAL_HOST.
+func NewDockerProvider() (*DockerProvider, error) {
+	binary := os.Getenv("DOCKER_BIN")
+	if binary == "" {
+		binary = "docker"
+	}
+	if _, err := exec.LookPath(binary); err != nil {
+		return nil, fmt.Errorf("%s not found: %v", binary, err)
+	}
+
+	host := os.Getenv("LOCAL_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	return &DockerProvider{
+		binary: binary,
+		host:   host,
+		ports:  NewPortAllocator(nil),
+	}, nil
+}
+
+func (d *DockerProvider) CreateChallengeInstance(instance *models.Instance, challenge *models.Challenge) error {
+	ctx := context.Background()
+
+	if instance.ID == 0 {
+		return fmt.Errorf("instance must be saved before creating its resources")
+	}
+
+	containerName := fmt.Sprintf("ctf-%d-%d-%d", instance.UserID, challenge.ID, instance.ID)
+	instance.PodName = containerName
+	flag, err := GenerateInstanceFlag(challenge.Flag, instance)
+	if err != nil {
+		return err
+	}
+	instance.Flag = flag
+
+	hostPort, err := d.ports.Allocate(ctx, containerName)
+	if err != nil {
+		return fmt.Errorf("failed to allocate host port: %w", err)
+	}
+
+	instance.ContainerName = containerName
+	instance.ExternalPort = hostPort
+	instance.InternalPort = challenge.Port
+
+	cpus := parseQuantity(challenge.CPULimit, "500m")
+	memory := parseQuantity(challenge.MemoryLimit, "512Mi")
+
+	_, err = d.run(ctx, "run", "-d",
+		"--name", containerName,
+		"--label", "app=ctf-challenge",
+		"--label", "user-id="+strconv.Itoa(instance.UserID),
+		"--label", "challenge-id="+strconv.Itoa(instance.ChallengeID),
+		"--label", "instance-id="+strconv.Itoa(instance.ID),
+		"-p", fmt.Sprintf("%d:%d", hostPort, challenge.Port),
+		"-e", "FLAG="+instance.Flag,
+		"--cpus", strconv.FormatFloat(float64(cpus.MilliValue())/1000, 'f', 3, 64),
+		"--memory", strconv.FormatInt(memory.Value(), 10),
+		"--security-opt", "no-new-privileges",
+		challenge.DockerImage)
+	if err != nil {
+		d.releasePort(containerName)
+		return fmt.Errorf("failed to start container: %v", err)
+	}
+
+	instance.AccessURL = fmt.Sprintf("http://%s:%d", d.host, hostPort)
+	log.Printf("Created local instance for user %d, challenge %d: %s", instance.UserID, instance.ChallengeID, instance.AccessURL)
+	return nil
+}
+
+func (d *DockerProvider) CleanupChallengeInstance(instance *models.Instance) error {
+	if instance.PodName == "" {
+		return nil
+	}
+
+	if _, err := d.run(context.Background(), "rm", "-f", instance.PodName); err != nil {
+		log.Printf("Failed to remove container %s: %v", instance.PodName, err)
+	}
+	d.releasePort(instance.PodName)
+
+	log.Printf("Deleted instance %d resources", instance.ID)
+	return nil
+}
+
+func (d *DockerProvider) RestartChallengeInstance(instance *models.Instance) error {
+	if _, err := d.run(context.Background(), "restart", instance.PodName); err != nil {
+		return fmt.Errorf("failed to restart container %s: %v", instance.PodName, err)
+	}
+	return nil
+}
+
+func (d *DockerProvider) ChallengeInstanceStatus(instance *models.Instance) (string, string, error) {
+	output, err := d.run(context.Background(), "inspect", "-f", "{{.State.Status}}|{{.State.ExitCode}}", instance.PodName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, exitCode, _ := strings.Cut(strings.TrimSpace(output), "|")
+	switch state {
+	case "running":
+		return StatusReady, "", nil
+	case "restarting":
+		return StatusCrashLoop, "Restarting", nil
+	case "exited", "dead":
+		return StatusFailed, fmt.Sprintf("Exited (%s)", exitCode), nil
+	default:
+		return StatusCreating, "", nil
+	}
+}
+
+func (d *DockerProvider) ChallengeInstanceLogs(instance *models.Instance, tailLines int64) (string, error) {
+	return d.run(context.Background(), "logs", "--tail", strconv.FormatInt(tailLines, 10), instance.PodName)
+}
+
+func (d *DockerProvider) releasePort(containerName string) {
+	if err := d.ports.Release(containerName); err != nil {
+		log.Printf("Failed to release host port of %s: %v", containerName, err)
+	}
+}
+
+// run exécute une commande docker et retourne sa sortie combinée
+func (d *DockerProvider) run(ctx context.Context, args ...string) (string, error) {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.binary, args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		return output.String(), fmt.Errorf("%s %s: %v: %s", d.binary, args[0], err, strings.TrimSpace(output.String()))
+	}
+	return output.String(), nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrEventNotRunning = errors.New("event is not running")
+	ErrEventNotPaused  = errors.New("event is not paused")
+)
+
+var (
+	// Le slug sert à nommer le namespace par défaut ("ctf-" + slug), d'où la limite
+	// de longueur des noms DNS
+	eventSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+	namespacePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+)
+
+// ValidateEventRequest vérifie le slug et le namespace d'un événement hébergé et
+// renseigne le namespace par défaut. Un événement sans slug s'applique à toute la
+// plateforme et n'a ni namespace ni mode de jeu propres.
+func ValidateEventRequest(req *models.EventRequest) error {
+	if req.Slug == "" {
+		if req.Namespace != "" {
+			return fmt.Errorf("namespace requires a slug")
+		}
+		if req.Mode != "" || req.ShareInstances != nil {
+			return fmt.Errorf("mode and share_instances require a slug")
+		}
+		return nil
+	}
+
+	if !eventSlugPattern.MatchString(req.Slug) {
+		return fmt.Errorf("invalid slug %q (up to 32 lowercase letters, digits and '-')", req.Slug)
+	}
+	if req.Namespace == "" {
+		req.Namespace = "ctf-" + req.Slug
+	}
+	if !namespacePattern.MatchString(req.Namespace) {
+		return fmt.Errorf("invalid namespace %q", req.Namespace)
+	}
+	return nil
+}
+
+// SetEventStatus renseigne l'état de l'événement et le gel du classement à la date now
+func SetEventStatus(event *models.Event, now time.Time) {
+	switch {
+	case event.PausedAt != nil:
+		event.Status = models.EventPaused
+	case now.Before(event.StartTime):
+		event.Status = models.EventScheduled
+	case !now.Before(event.EndTime):
+		event.Status = models.EventEnded
+	default:
+		event.Status = models.EventRunning
+	}
+	// Le classement reste gelé après la fin, jusqu'à ce que la date de gel soit retirée
+	event.Frozen = event.FreezeTime != nil && !now.Before(*event.FreezeTime)
+}
+
+// CurrentEvent récupère l'événement en cours avec son état, nil si aucun événement
+// n'est configuré
+func CurrentEvent() (*models.Event, error) {
+	event, err := db.GetCurrentEvent()
+	if err != nil || event == nil {
+		return nil, err
+	}
+	SetEventStatus(event, time.Now())
+	return event, nil
+}
+
+// EventBySlug récupère un événement hébergé avec son état, nil s'il n'existe pas
+func EventBySlug(slug string) (*models.Event, error) {
+	event, err := db.GetEventBySlug(slug)
+	if err != nil || event == nil {
+		return nil, err
+	}
+	SetEventStatus(event, time.Now())
+	return event, nil
+}
+
+// ChallengeEvent récupère avec son état l'événement qui régit un challenge : son
+// événement hébergé s'il en a un, sinon l'événement de la plateforme (nil si aucun)
+func ChallengeEvent(challenge *models.Challenge) (*models.Event, error) {
+	if challenge.EventID == nil {
+		return CurrentEvent()
+	}
+
+	event, err := db.GetEventByID(*challenge.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, fmt.Errorf("event %d of challenge %d not found", *challenge.EventID, challenge.ID)
+	}
+	SetEventStatus(event, time.Now())
+	return event, nil
+}
+
+// ScoreboardFreeze retourne la date de gel du classement public, nil s'il n'est pas gelé
+func ScoreboardFreeze() (*time.Time, error) {
+	event, err := CurrentEvent()
+	if err != nil || event == nil || !event.Frozen {
+		return nil, err
+	}
+	return event.FreezeTime, nil
+}
+
+// PauseEvent suspend un événement en cours
+func PauseEvent(event *models.Event, now time.Time) error {
+	SetEventStatus(event, now)
+	if event.Status != models.EventRunning {
+		return ErrEventNotRunning
+	}
+
+	event.PausedAt = &now
+	SetEventStatus(event, now)
+	return db.UpdateEvent(event)
+}
+
+// ResumeEvent reprend un événement suspendu. La fin de l'événement, et son gel s'il
+// n'était pas encore atteint, sont repoussés de la durée de la pause.
+func ResumeEvent(event *models.Event, now time.Time) error {
+	if event.PausedAt == nil {
+		return ErrEventNotPaused
+	}
+
+	paused := now.Sub(*event.PausedAt)
+	if event.FreezeTime != nil && event.FreezeTime.After(*event.PausedAt) {
+		freezeTime := event.FreezeTime.Add(paused)
+		event.FreezeTime = &freezeTime
+	}
+	event.EndTime = event.EndTime.Add(paused)
+	event.PausedAt = nil
+
+	SetEventStatus(event, now)
+	return db.UpdateEvent(event)
+}
+
+// ExtendEvent repousse la fin d'un événement
+func ExtendEvent(event *models.Event, duration time.Duration, now time.Time) error {
+	event.EndTime = event.EndTime.Add(duration)
+	SetEventStatus(event, now)
+	return db.UpdateEvent(event)
+}
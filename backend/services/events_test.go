@@ -0,0 +1,64 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"testing"
+	"time"
+)
+
+func TestSetEventStatus(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	freeze := start.Add(7 * time.Hour)
+	event := &models.Event{StartTime: start, EndTime: start.Add(8 * time.Hour), FreezeTime: &freeze}
+
+	tests := []struct {
+		at     time.Time
+		status string
+		frozen bool
+	}{
+		{start.Add(-time.Minute), models.EventScheduled, false},
+		{start, models.EventRunning, false},
+		{freeze, models.EventRunning, true},
+		{start.Add(8 * time.Hour), models.EventEnded, true},
+	}
+	for _, tt := range tests {
+		SetEventStatus(event, tt.at)
+		if event.Status != tt.status || event.Frozen != tt.frozen {
+			t.Errorf("at %s: status %q frozen %t, want %q %t", tt.at, event.Status, event.Frozen, tt.status, tt.frozen)
+		}
+	}
+}
+
+func TestPauseAndResumeEventShiftsEnd(t *testing.T) {
+	setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	freeze := now.Add(time.Hour)
+	event := &models.Event{Name: "finale", StartTime: now.Add(-time.Hour), EndTime: now.Add(2 * time.Hour), FreezeTime: &freeze}
+	if err := db.CreateEvent(event); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+
+	if err := ResumeEvent(event, now); err != ErrEventNotPaused {
+		t.Errorf("ResumeEvent on running event: err = %v, want ErrEventNotPaused", err)
+	}
+	if err := PauseEvent(event, now); err != nil {
+		t.Fatalf("PauseEvent: %v", err)
+	}
+
+	current, err := CurrentEvent()
+	if err != nil || current == nil || current.Status != models.EventPaused {
+		t.Fatalf("CurrentEvent after pause = %+v, %v", current, err)
+	}
+
+	if err := ResumeEvent(current, now.Add(30*time.Minute)); err != nil {
+		t.Fatalf("ResumeEvent: %v", err)
+	}
+	stored, err := db.GetEventByID(event.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("GetEventByID: %v", err)
+	}
+	if !stored.EndTime.Equal(now.Add(150*time.Minute)) || !stored.FreezeTime.Equal(now.Add(90*time.Minute)) || stored.PausedAt != nil {
+		t.Errorf("after resume: end %s, freeze %s, paused %v", stored.EndTime, stored.FreezeTime, stored.PausedAt)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportPageSize est la taille des pages du classement lues pour un export complet
+const exportPageSize = 500
+
+// CTFtimeScoreboard construit le classement complet de la plateforme (eventID 0) ou
+// d'un événement hébergé au format d'import de CTFtime : classement des équipes en
+// mode équipe, des joueurs sinon. Si frozenAt est défini, le classement est celui de
+// cette date. Le mode de jeu est celui de l'événement exporté.
+func CTFtimeScoreboard(eventID int, frozenAt *time.Time) (*models.CTFtimeScoreboard, error) {
+	settings, err := db.GetScopedSettings(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreboard := &models.CTFtimeScoreboard{Standings: []models.CTFtimeStanding{}}
+	for offset := 0; ; offset += exportPageSize {
+		var page []models.CTFtimeStanding
+		if settings.Mode == models.ModeTeam {
+			page, err = teamStandings(eventID, offset, frozenAt)
+		} else {
+			page, err = playerStandings(eventID, offset, frozenAt)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scoreboard.Standings = append(scoreboard.Standings, page...)
+		if len(page) < exportPageSize {
+			return scoreboard, nil
+		}
+	}
+}
+
+func playerStandings(eventID, offset int, frozenAt *time.Time) ([]models.CTFtimeStanding, error) {
+	var entries []models.LeaderboardEntry
+	var err error
+	if eventID != 0 {
+		entries, _, err = db.GetEventLeaderboard(eventID, exportPageSize, offset, frozenAt)
+	} else {
+		entries, _, err = db.GetLeaderboard(exportPageSize, offset, frozenAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	standings := make([]models.CTFtimeStanding, len(entries))
+	for i, entry := range entries {
+		standings[i] = models.CTFtimeStanding{Pos: entry.Rank, Team: entry.Username, Score: entry.Score}
+	}
+	return standings, nil
+}
+
+func teamStandings(eventID, offset int, frozenAt *time.Time) ([]models.CTFtimeStanding, error) {
+	var entries []models.TeamLeaderboardEntry
+	var err error
+	if eventID != 0 {
+		entries, _, err = db.GetEventTeamLeaderboard(eventID, exportPageSize, offset, frozenAt)
+	} else {
+		entries, _, err = db.GetTeamLeaderboard(exportPageSize, offset, frozenAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	standings := make([]models.CTFtimeStanding, len(entries))
+	for i, entry := range entries {
+		standings[i] = models.CTFtimeStanding{Pos: entry.Rank, Team: entry.Name, Score: entry.Score}
+	}
+	return standings, nil
+}
+
+// WriteSolvesCSV écrit l'historique des résolutions au format CSV, une ligne par
+// résolution précédée d'un en-tête
+func WriteSolvesCSV(w io.Writer, solves []models.ChallengeSolve) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "solved_at", "user_id", "username", "team_id", "team_name",
+		"challenge_id", "challenge_name", "event_id", "event_slug", "solve_rank", "bonus"})
+	for _, solve := range solves {
+		out.Write([]string{
+			strconv.Itoa(solve.ID),
+			solve.SolvedAt.UTC().Format(time.RFC3339),
+			strconv.Itoa(solve.UserID),
+			csvText(solve.Username),
+			optionalID(solve.TeamID),
+			csvText(solve.TeamName),
+			strconv.Itoa(solve.ChallengeID),
+			csvText(solve.ChallengeName),
+			optionalID(solve.EventID),
+			csvText(solve.EventSlug),
+			strconv.Itoa(solve.SolveRank),
+			strconv.Itoa(solve.Bonus),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// csvText neutralise un texte saisi par un joueur qu'un tableur interpréterait comme
+// une formule (=, +, -, @, tabulation ou retour chariot en tête)
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func optionalID(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCTFtimeScoreboard(t *testing.T) {
+	setupTestDB(t)
+
+	users := []int{createTestUser(t, "alice"), createTestUser(t, "bob")}
+
+	for _, challengeID := range []int{1, 2} {
+		submission := &models.Submission{UserID: users[1], ChallengeID: challengeID, SubmittedFlag: "flag", IsValid: true}
+		if _, err := db.RecordSubmission(submission); err != nil {
+			t.Fatalf("RecordSubmission: %v", err)
+		}
+	}
+
+	scoreboard, err := CTFtimeScoreboard(0, nil)
+	if err != nil {
+		t.Fatalf("CTFtimeScoreboard: %v", err)
+	}
+	want := []models.CTFtimeStanding{
+		{Pos: 1, Team: "bob", Score: 150},
+		{Pos: 2, Team: "alice", Score: 0},
+	}
+	if !reflect.DeepEqual(scoreboard.Standings, want) {
+		t.Errorf("standings = %+v, want %+v", scoreboard.Standings, want)
+	}
+
+}
+
+func TestWriteSolvesCSV(t *testing.T) {
+	teamID, eventID := 3, 4
+	solves := []models.ChallengeSolve{
+		{ID: 1, UserID: 2, Username: "=HYPERLINK(\"http://evil\")", TeamID: &teamID, TeamName: "@team",
+			ChallengeID: 5, ChallengeName: "-1+1", EventID: &eventID, EventSlug: "weekly",
+			SolveRank: 1, Bonus: 50, SolvedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{ID: 2, UserID: 6, Username: "bob", ChallengeID: 7, ChallengeName: "Web 1",
+			SolveRank: 2, SolvedAt: time.Date(2026, 1, 2, 4, 0, 0, 0, time.UTC)},
+	}
+
+	var out bytes.Buffer
+	if err := WriteSolvesCSV(&out, solves); err != nil {
+		t.Fatalf("WriteSolvesCSV: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"id,solved_at,user_id,username,team_id,team_name,challenge_id,challenge_name,event_id,event_slug,solve_rank,bonus",
+		`1,2026-01-02T03:04:05Z,2,"'=HYPERLINK(""http://evil"")",3,'@team,5,'-1+1,4,weekly,1,50`,
+		"2,2026-01-02T04:00:00Z,6,bob,,,7,Web 1,,,2,0",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", out.String(), want)
+	}
+}
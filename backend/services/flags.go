@@ -0,0 +1,136 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrFlagSecretMissing signale l'absence de FLAG_SECRET : sans secret, les flags
+// d'instance seraient calculables par n'importe qui à partir du nom du pod
+var ErrFlagSecretMissing = errors.New("FLAG_SECRET is not set")
+
+// GenerateInstanceFlag dérive un flag unique pour une instance à partir du flag
+// statique du challenge et d'un HMAC de l'instance, de l'utilisateur et du challenge.
+// Le format PREFIXE{...} du flag statique est conservé : CTF{xss} devient CTF{xss_<hmac>}.
+func GenerateInstanceFlag(baseFlag string, instance *models.Instance) (string, error) {
+	secret, err := flagSecret()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s:%d:%d", instance.PodName, instance.UserID, instance.ChallengeID)
+	suffix := hex.EncodeToString(mac.Sum(nil))[:24]
+
+	open := strings.Index(baseFlag, "{")
+	if open > 0 && strings.HasSuffix(baseFlag, "}") {
+		return fmt.Sprintf("%s_%s}", strings.TrimSuffix(baseFlag, "}"), suffix), nil
+	}
+	return fmt.Sprintf("CTF{%s}", suffix), nil
+}
+
+// CheckFlagSecret vérifie au démarrage que FLAG_SECRET est défini
+func CheckFlagSecret() error {
+	_, err := flagSecret()
+	return err
+}
+
+// flagSecret retourne le secret serveur utilisé pour signer les flags d'instance. Il
+// est propre aux flags : le secret des sessions (JWT_SECRET) n'est pas réutilisé.
+func flagSecret() ([]byte, error) {
+	secret := os.Getenv("FLAG_SECRET")
+	if secret == "" {
+		return nil, ErrFlagSecretMissing
+	}
+	return []byte(secret), nil
+}
+
+// ReportSharedFlag signale pour revue une soumission incorrecte qui correspond au
+// flag de l'instance d'un autre joueur
+func ReportSharedFlag(submission *models.Submission) {
+	owner, err := db.FindInstanceByFlag(submission.ChallengeID, submission.SubmittedFlag, submission.UserID)
+	if err != nil {
+		log.Printf("Error looking up shared flag for submission %d: %v", submission.ID, err)
+		return
+	}
+	if owner == nil {
+		return
+	}
+
+	review := &models.FlagReview{
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		ChallengeID:  submission.ChallengeID,
+		InstanceID:   owner.ID,
+		OwnerID:      owner.UserID,
+	}
+	if err := db.CreateFlagReview(review); err != nil {
+		log.Printf("Error creating flag review for submission %d: %v", submission.ID, err)
+		return
+	}
+
+	log.Printf("Flag partagé détecté: l'utilisateur %d a soumis le flag de l'instance %d (utilisateur %d)",
+		submission.UserID, owner.ID, owner.UserID)
+}
+
+// ValidateFlag vérifie le contenu d'un flag supplémentaire. Une expression régulière
+// doit compiler ; elle est évaluée sur le flag soumis entier.
+func ValidateFlag(flag *models.Flag) error {
+	if strings.TrimSpace(flag.Content) == "" || len(flag.Content) > 255 {
+		return fmt.Errorf("content must be between 1 and 255 characters")
+	}
+	if flag.Points < 0 {
+		return fmt.Errorf("points cannot be negative")
+	}
+
+	switch flag.Type {
+	case models.FlagStatic:
+		return nil
+	case models.FlagRegex:
+		if _, err := compileFlagPattern(flag); err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown flag type %q", flag.Type)
+}
+
+// MatchFlag indique si un flag soumis correspond à un flag supplémentaire. Les flags
+// statiques sont comparés en temps constant.
+func MatchFlag(flag *models.Flag, submitted string) bool {
+	switch flag.Type {
+	case models.FlagStatic:
+		expected := flag.Content
+		if !flag.CaseSensitive {
+			expected, submitted = strings.ToLower(expected), strings.ToLower(submitted)
+		}
+		return subtle.ConstantTimeCompare([]byte(submitted), []byte(expected)) == 1
+	case models.FlagRegex:
+		pattern, err := compileFlagPattern(flag)
+		if err != nil {
+			log.Printf("Invalid regex for flag %d: %v", flag.ID, err)
+			return false
+		}
+		return pattern.MatchString(submitted)
+	}
+	return false
+}
+
+// compileFlagPattern compile l'expression d'un flag regex, ancrée sur le flag entier
+func compileFlagPattern(flag *models.Flag) (*regexp.Regexp, error) {
+	expr := `^(?:` + flag.Content + `)$`
+	if !flag.CaseSensitive {
+		expr = `(?i)` + expr
+	}
+	return regexp.Compile(expr)
+}
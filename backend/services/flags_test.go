@@ -0,0 +1,108 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMatchFlag(t *testing.T) {
+	tests := []struct {
+		name      string
+		flag      models.Flag
+		submitted string
+		want      bool
+	}{
+		{"static exact", models.Flag{Type: models.FlagStatic, Content: "CTF{abc}", CaseSensitive: true}, "CTF{abc}", true},
+		{"static wrong case", models.Flag{Type: models.FlagStatic, Content: "CTF{abc}", CaseSensitive: true}, "ctf{ABC}", false},
+		{"static case-insensitive", models.Flag{Type: models.FlagStatic, Content: "CTF{abc}"}, "ctf{ABC}", true},
+		{"regex", models.Flag{Type: models.FlagRegex, Content: `CTF\{[0-9]{4}\}`, CaseSensitive: true}, "CTF{1337}", true},
+		{"regex is anchored", models.Flag{Type: models.FlagRegex, Content: `CTF\{[0-9]{4}\}`, CaseSensitive: true}, "xCTF{1337}x", false},
+		{"regex alternation is anchored", models.Flag{Type: models.FlagRegex, Content: `CTF\{a\}|CTF\{b\}`, CaseSensitive: true}, "CTF{b}junk", false},
+		{"regex case-insensitive", models.Flag{Type: models.FlagRegex, Content: `ctf\{hello\}`}, "CTF{HELLO}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchFlag(&tt.flag, tt.submitted); got != tt.want {
+				t.Errorf("MatchFlag(%q) = %t, want %t", tt.submitted, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFlagRejectsInvalidRegex(t *testing.T) {
+	flag := &models.Flag{Type: models.FlagRegex, Content: `CTF\{(unclosed\}`}
+	if err := ValidateFlag(flag); err == nil {
+		t.Error("ValidateFlag accepted an invalid regex")
+	}
+}
+
+func TestGenerateInstanceFlag(t *testing.T) {
+	t.Setenv("FLAG_SECRET", "test-secret")
+	instance := &models.Instance{PodName: "ctf-2-1-7", UserID: 2, ChallengeID: 1}
+
+	flag, err := GenerateInstanceFlag("CTF{xss}", instance)
+	if err != nil {
+		t.Fatalf("GenerateInstanceFlag: %v", err)
+	}
+	if !strings.HasPrefix(flag, "CTF{xss_") || !strings.HasSuffix(flag, "}") || len(flag) != len("CTF{xss_}")+24 {
+		t.Errorf("flag = %q, want CTF{xss_<24 hex chars>}", flag)
+	}
+	if again, _ := GenerateInstanceFlag("CTF{xss}", instance); again != flag {
+		t.Errorf("flag is not deterministic: %q then %q", flag, again)
+	}
+
+	other := &models.Instance{PodName: "ctf-3-1-8", UserID: 3, ChallengeID: 1}
+	if otherFlag, _ := GenerateInstanceFlag("CTF{xss}", other); otherFlag == flag {
+		t.Error("two instances share the same flag")
+	}
+
+	t.Setenv("FLAG_SECRET", "another-secret")
+	if rotated, _ := GenerateInstanceFlag("CTF{xss}", instance); rotated == flag {
+		t.Error("flag does not depend on FLAG_SECRET")
+	}
+
+	t.Setenv("FLAG_SECRET", "")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	if _, err := GenerateInstanceFlag("CTF{xss}", instance); !errors.Is(err, ErrFlagSecretMissing) {
+		t.Errorf("GenerateInstanceFlag without FLAG_SECRET = %v, want ErrFlagSecretMissing", err)
+	}
+}
+
+func TestReportSharedFlag(t *testing.T) {
+	setupTestDB(t)
+	provider := NewMemoryProvider()
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	createTestInstance(t, provider, alice)
+	bobInstance := createTestInstance(t, provider, bob)
+
+	// Alice soumet le flag de l'instance de Bob
+	submission := &models.Submission{UserID: alice, ChallengeID: 1, SubmittedFlag: bobInstance.Flag}
+	if _, err := db.RecordSubmission(submission); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+	ReportSharedFlag(submission)
+
+	// Bob soumet son propre flag : rien à signaler
+	own := &models.Submission{UserID: bob, ChallengeID: 1, SubmittedFlag: bobInstance.Flag}
+	if _, err := db.RecordSubmission(own); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+	ReportSharedFlag(own)
+
+	reviews, err := db.GetFlagReviews()
+	if err != nil {
+		t.Fatalf("GetFlagReviews: %v", err)
+	}
+	if len(reviews) != 1 {
+		t.Fatalf("reviews = %+v, want one review", reviews)
+	}
+	review := reviews[0]
+	if review.UserID != alice || review.OwnerID != bob || review.InstanceID != bobInstance.ID || review.SubmissionID != submission.ID {
+		t.Errorf("review = %+v, want alice reported for bob's instance %d", review, bobInstance.ID)
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Modes d'exposition des instances
+const (
+	ExposureNodePort = "nodeport"
+	ExposureIngress  = "ingress"
+)
+
+// ErrNodeHostMissing signale l'absence de CLUSTER_IP en mode nodeport : sans elle,
+// les joueurs recevraient l'adresse d'instances injoignables
+var ErrNodeHostMissing = errors.New("CLUSTER_IP is not set")
+
+// ExposureConfig décrit comment les instances sont exposées aux joueurs
+type ExposureConfig struct {
+	Mode         string // nodeport ou ingress
+	NodeHost     string // Adresse des nœuds annoncée aux joueurs en mode nodeport
+	Domain       string // Domaine parent des sous-domaines d'instance, ex: chall.example.org
+	TLSSecret    string // Secret TLS (certificat wildcard) optionnel
+	IngressClass string // IngressClass optionnelle
+}
+
+// LoadExposureConfig lit la configuration d'exposition depuis EXPOSURE_MODE,
+// CLUSTER_IP, INGRESS_DOMAIN, INGRESS_TLS_SECRET et INGRESS_CLASS
+func LoadExposureConfig() ExposureConfig {
+	config := ExposureConfig{
+		Mode:         strings.ToLower(os.Getenv("EXPOSURE_MODE")),
+		NodeHost:     os.Getenv("CLUSTER_IP"),
+		Domain:       strings.Trim(os.Getenv("INGRESS_DOMAIN"), "."),
+		TLSSecret:    os.Getenv("INGRESS_TLS_SECRET"),
+		IngressClass: os.Getenv("INGRESS_CLASS"),
+	}
+
+	if config.Mode == "" {
+		config.Mode = ExposureNodePort
+	}
+	if config.Mode != ExposureNodePort && config.Mode != ExposureIngress {
+		log.Printf("Unknown EXPOSURE_MODE %q, using %s", config.Mode, ExposureNodePort)
+		config.Mode = ExposureNodePort
+	}
+	if config.Mode == ExposureIngress && config.Domain == "" {
+		log.Printf("INGRESS_DOMAIN not set, using %s exposure", ExposureNodePort)
+		config.Mode = ExposureNodePort
+	}
+	return config
+}
+
+// ingressName retourne le nom de l'Ingress associé à une instance
+func ingressName(podName string) string {
+	return fmt.Sprintf("ing-%s", podName)
+}
+
+// randomSubdomain génère un sous-domaine aléatoire difficile à deviner
+func randomSubdomain() (string, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// createIngress expose le Service d'une instance sur un sous-domaine aléatoire
+// et retourne l'URL d'accès correspondante
+func (k *KubernetesService) createIngress(ctx context.Context, instance *models.Instance) (string, error) {
+	token, err := randomSubdomain()
+	if err != nil {
+		return "", err
+	}
+	host := fmt.Sprintf("%s.%s", token, k.exposure.Domain)
+	pathType := networkingv1.PathTypePrefix
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ingressName(instance.PodName),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				"app":         "ctf-challenge",
+				"instance-id": strconv.Itoa(instance.ID),
+			},
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: instance.ServiceName,
+											Port: networkingv1.ServiceBackendPort{
+												Number: int32(instance.InternalPort),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if k.exposure.IngressClass != "" {
+		ingress.Spec.IngressClassName = &k.exposure.IngressClass
+	}
+
+	scheme := "http"
+	if k.exposure.TLSSecret != "" {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{
+			{
+				Hosts:      []string{host},
+				SecretName: k.exposure.TLSSecret,
+			},
+		}
+		scheme = "https"
+	}
+
+	if _, err := k.clientset.NetworkingV1().Ingresses(instance.Namespace).Create(ctx, ingress, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s://%s", scheme, host), nil
+}
+
+// deleteIngress supprime l'Ingress d'une instance s'il existe
+func (k *KubernetesService) deleteIngress(ctx context.Context, namespace, podName string) error {
+	err := k.clientset.NetworkingV1().Ingresses(namespace).Delete(ctx, ingressName(podName), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestIngressExposure(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("EXPOSURE_MODE", "ingress")
+	t.Setenv("INGRESS_DOMAIN", "chall.example.org")
+	t.Setenv("INGRESS_TLS_SECRET", "wildcard-tls")
+	k8sService, clientset := setupTestKubernetes(t)
+	ctx := context.Background()
+
+	instance := createTestInstance(t, k8sService, 1)
+
+	if !strings.HasPrefix(instance.AccessURL, "https://") || !strings.HasSuffix(instance.AccessURL, ".chall.example.org") {
+		t.Errorf("AccessURL = %q, want https://<token>.chall.example.org", instance.AccessURL)
+	}
+	if instance.ExternalPort != 0 {
+		t.Errorf("ExternalPort = %d, want 0 in ingress mode", instance.ExternalPort)
+	}
+
+	service, err := clientset.CoreV1().Services("ctf-test").Get(ctx, instance.ServiceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if service.Spec.Type != corev1.ServiceTypeClusterIP {
+		t.Errorf("service type = %s, want ClusterIP", service.Spec.Type)
+	}
+
+	ingress, err := clientset.NetworkingV1().Ingresses("ctf-test").Get(ctx, ingressName(instance.PodName), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if host := ingress.Spec.Rules[0].Host; "https://"+host != instance.AccessURL {
+		t.Errorf("ingress host = %q, AccessURL = %q", host, instance.AccessURL)
+	}
+	if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != "wildcard-tls" {
+		t.Errorf("ingress TLS = %+v, want secret wildcard-tls", ingress.Spec.TLS)
+	}
+
+	if err := k8sService.CleanupChallengeInstance(instance); err != nil {
+		t.Fatalf("CleanupChallengeInstance: %v", err)
+	}
+	if _, err := clientset.NetworkingV1().Ingresses("ctf-test").Get(ctx, ingressName(instance.PodName), metav1.GetOptions{}); err == nil {
+		t.Error("ingress still exists after cleanup")
+	}
+}
+
+func TestNodePortExposureRequiresHost(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("EXPOSURE_MODE", "nodeport")
+	t.Setenv("CLUSTER_IP", "")
+	if _, err := NewKubernetesServiceWithClient(fake.NewClientset(), "ctf-test"); !errors.Is(err, ErrNodeHostMissing) {
+		t.Fatalf("NewKubernetesServiceWithClient without CLUSTER_IP = %v, want ErrNodeHostMissing", err)
+	}
+
+	k8sService, _ := setupTestKubernetes(t)
+	instance := createTestInstance(t, k8sService, 1)
+	if want := fmt.Sprintf("http://10.0.0.1:%d", instance.ExternalPort); instance.AccessURL != want {
+		t.Errorf("AccessURL = %q, want %q", instance.AccessURL, want)
+	}
+}
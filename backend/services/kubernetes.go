@@ -5,15 +5,16 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -21,8 +22,13 @@ import (
 )
 
 type KubernetesService struct {
-	clientset *kubernetes.Clientset
-	namespace string
+	clientset kubernetes.Interface
+	namespace string // Namespace par défaut, celui des instances hors événement hébergé
+	ports     *PortAllocator
+	exposure  ExposureConfig
+
+	mu         sync.Mutex
+	namespaces map[string]bool // Namespaces dont l'existence a été vérifiée
 }
 
 func NewKubernetesService() (*KubernetesService, error) {
@@ -51,25 +57,44 @@ func NewKubernetesService() (*KubernetesService, error) {
 		namespace = "ctf-instances"
 	}
 
+	return NewKubernetesServiceWithClient(clientset, namespace)
+}
+
+// NewKubernetesServiceWithClient construit le service à partir d'un client existant
+// (clientset factice dans les tests par exemple)
+func NewKubernetesServiceWithClient(clientset kubernetes.Interface, namespace string) (*KubernetesService, error) {
 	k8sService := &KubernetesService{
-		clientset: clientset,
-		namespace: namespace,
+		clientset:  clientset,
+		namespace:  namespace,
+		ports:      NewPortAllocator(clientset),
+		exposure:   LoadExposureConfig(),
+		namespaces: make(map[string]bool),
+	}
+	if k8sService.exposure.Mode == ExposureNodePort && k8sService.exposure.NodeHost == "" {
+		return nil, ErrNodeHostMissing
 	}
 
-	if err := k8sService.ensureNamespace(); err != nil {
+	if err := k8sService.ensureNamespace(namespace); err != nil {
 		return nil, fmt.Errorf("failed to ensure namespace: %v", err)
 	}
 
 	return k8sService, nil
 }
 
-func (k *KubernetesService) ensureNamespace() error {
+// ensureNamespace crée le namespace s'il n'existe pas encore
+func (k *KubernetesService) ensureNamespace(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.namespaces[name] {
+		return nil
+	}
+
 	ctx := context.Background()
-	_, err := k.clientset.CoreV1().Namespaces().Get(ctx, k.namespace, metav1.GetOptions{})
+	_, err := k.clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		namespace := &corev1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: k.namespace,
+				Name: name,
 				Labels: map[string]string{
 					"purpose": "ctf-challenges",
 				},
@@ -79,38 +104,80 @@ func (k *KubernetesService) ensureNamespace() error {
 		if err != nil {
 			return err
 		}
-		log.Printf("Created namespace: %s", k.namespace)
+		log.Printf("Created namespace: %s", name)
 	}
+	k.namespaces[name] = true
 	return nil
 }
 
+// instanceNamespace retourne le namespace des ressources d'une instance
+func (k *KubernetesService) instanceNamespace(instance *models.Instance) string {
+	if instance.Namespace != "" {
+		return instance.Namespace
+	}
+	return k.namespace
+}
+
 func (k *KubernetesService) CreateChallengeInstance(instance *models.Instance, challenge *models.Challenge) error {
 	ctx := context.Background()
 
-	podName := fmt.Sprintf("ctf-%d-%d-%d", instance.UserID, challenge.ID, time.Now().Unix()%10000)
+	if instance.ID == 0 {
+		return fmt.Errorf("instance must be saved before creating its resources")
+	}
+
+	// Les instances d'un événement hébergé sont créées dans le namespace de l'événement
+	namespace := k.instanceNamespace(instance)
+	if err := k.ensureNamespace(namespace); err != nil {
+		return fmt.Errorf("failed to ensure namespace %s: %v", namespace, err)
+	}
+
+	podName := fmt.Sprintf("ctf-%d-%d-%d", instance.UserID, challenge.ID, instance.ID)
 	serviceName := fmt.Sprintf("svc-%s", podName)
-	externalPort := 30000 + rand.Intn(2000)
 
 	instance.PodName = podName
+	flag, err := GenerateInstanceFlag(challenge.Flag, instance)
+	if err != nil {
+		return err
+	}
+	instance.Flag = flag
+
+	// En mode ingress, le Service reste interne au cluster : pas de NodePort à réserver
+	externalPort := 0
+	if k.exposure.Mode == ExposureNodePort {
+		port, err := k.ports.Allocate(ctx, podName)
+		if err != nil {
+			return fmt.Errorf("failed to allocate node port: %w", err)
+		}
+		externalPort = port
+	}
+
 	instance.ServiceName = serviceName
 	instance.ExternalPort = externalPort
 	instance.InternalPort = challenge.Port
-	instance.Namespace = k.namespace
+	instance.Namespace = namespace
 
 	if err := k.createDeployment(ctx, instance, challenge); err != nil {
+		k.releasePort(podName)
 		return fmt.Errorf("failed to create deployment: %v", err)
 	}
 
 	if err := k.createService(ctx, instance); err != nil {
-		k.deleteDeployment(ctx, podName)
+		k.deleteDeployment(ctx, namespace, podName)
+		k.releasePort(podName)
 		return fmt.Errorf("failed to create service: %v", err)
 	}
 
-	clusterIP := os.Getenv("CLUSTER_IP")
-	if clusterIP == "" {
-		clusterIP = "192.168.1.34"
+	if k.exposure.Mode == ExposureIngress {
+		accessURL, err := k.createIngress(ctx, instance)
+		if err != nil {
+			k.clientset.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
+			k.deleteDeployment(ctx, namespace, podName)
+			return fmt.Errorf("failed to create ingress: %v", err)
+		}
+		instance.AccessURL = accessURL
+	} else {
+		instance.AccessURL = fmt.Sprintf("http://%s:%d", k.exposure.NodeHost, externalPort)
 	}
-	instance.AccessURL = fmt.Sprintf("http://%s:%d", clusterIP, externalPort)
 
 	log.Printf("Created instance for user %d, challenge %d: %s", instance.UserID, instance.ChallengeID, instance.AccessURL)
 	return nil
@@ -122,12 +189,13 @@ func (k *KubernetesService) createDeployment(ctx context.Context, instance *mode
 		"user-id":      strconv.Itoa(instance.UserID),
 		"challenge-id": strconv.Itoa(instance.ChallengeID),
 		"instance-id":  strconv.Itoa(instance.ID),
+		"pod-name":     instance.PodName,
 	}
 
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      instance.PodName,
-			Namespace: k.namespace,
+			Namespace: instance.Namespace,
 			Labels:    labels,
 		},
 		Spec: appsv1.DeploymentSpec{
@@ -150,6 +218,12 @@ func (k *KubernetesService) createDeployment(ctx context.Context, instance *mode
 									Protocol:      corev1.ProtocolTCP,
 								},
 							},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "FLAG",
+									Value: instance.Flag,
+								},
+							},
 							Resources: corev1.ResourceRequirements{
 								Limits: corev1.ResourceList{
 									corev1.ResourceCPU:    parseQuantity(challenge.CPULimit, "500m"),
@@ -178,7 +252,7 @@ func (k *KubernetesService) createDeployment(ctx context.Context, instance *mode
 		},
 	}
 
-	_, err := k.clientset.AppsV1().Deployments(k.namespace).Create(ctx, deployment, metav1.CreateOptions{})
+	_, err := k.clientset.AppsV1().Deployments(instance.Namespace).Create(ctx, deployment, metav1.CreateOptions{})
 	return err
 }
 
@@ -186,7 +260,7 @@ func (k *KubernetesService) createService(ctx context.Context, instance *models.
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      instance.ServiceName,
-			Namespace: k.namespace,
+			Namespace: instance.Namespace,
 			Labels: map[string]string{
 				"app":         "ctf-challenge",
 				"instance-id": strconv.Itoa(instance.ID),
@@ -209,38 +283,74 @@ func (k *KubernetesService) createService(ctx context.Context, instance *models.
 		},
 	}
 
-	_, err := k.clientset.CoreV1().Services(k.namespace).Create(ctx, service, metav1.CreateOptions{})
+	// En mode ingress, le Service n'est accessible qu'à travers l'Ingress
+	if k.exposure.Mode == ExposureIngress {
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+		service.Spec.Ports[0].NodePort = 0
+	}
+
+	_, err := k.clientset.CoreV1().Services(instance.Namespace).Create(ctx, service, metav1.CreateOptions{})
 	return err
 }
 
 func (k *KubernetesService) DeleteInstance(instance *models.Instance) error {
 	ctx := context.Background()
+	namespace := k.instanceNamespace(instance)
+
+	if instance.PodName != "" {
+		if err := k.deleteIngress(ctx, namespace, instance.PodName); err != nil {
+			log.Printf("Failed to delete ingress of %s: %v", instance.PodName, err)
+		}
+	}
 
 	if instance.ServiceName != "" {
-		err := k.clientset.CoreV1().Services(k.namespace).Delete(ctx, instance.ServiceName, metav1.DeleteOptions{})
+		err := k.clientset.CoreV1().Services(namespace).Delete(ctx, instance.ServiceName, metav1.DeleteOptions{})
 		if err != nil {
 			log.Printf("Failed to delete service %s: %v", instance.ServiceName, err)
 		}
 	}
 
 	if instance.PodName != "" {
-		err := k.deleteDeployment(ctx, instance.PodName)
+		err := k.deleteDeployment(ctx, namespace, instance.PodName)
 		if err != nil {
 			log.Printf("Failed to delete deployment %s: %v", instance.PodName, err)
 		}
+		k.releasePort(instance.PodName)
 	}
 
 	log.Printf("Deleted instance %d resources", instance.ID)
 	return nil
 }
 
-func (k *KubernetesService) deleteDeployment(ctx context.Context, name string) error {
+func (k *KubernetesService) deleteDeployment(ctx context.Context, namespace, name string) error {
 	deletePolicy := metav1.DeletePropagationForeground
-	return k.clientset.AppsV1().Deployments(k.namespace).Delete(ctx, name, metav1.DeleteOptions{
+	return k.clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{
 		PropagationPolicy: &deletePolicy,
 	})
 }
 
+func (k *KubernetesService) releasePort(podName string) {
+	if err := k.ports.Release(podName); err != nil {
+		log.Printf("Failed to release node port of %s: %v", podName, err)
+	}
+}
+
+// RestartChallengeInstance recrée le pod d'une instance en déclenchant un redémarrage du Deployment
+func (k *KubernetesService) RestartChallengeInstance(instance *models.Instance) error {
+	ctx := context.Background()
+
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":%q}}}}}`,
+		time.Now().Format(time.RFC3339))
+	_, err := k.clientset.AppsV1().Deployments(k.instanceNamespace(instance)).Patch(ctx, instance.PodName,
+		types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to restart deployment %s: %v", instance.PodName, err)
+	}
+
+	log.Printf("Restarted instance %d (%s)", instance.ID, instance.PodName)
+	return nil
+}
+
 // ✅ Nouvelle méthode ajoutée ici
 func (k *KubernetesService) CleanupChallengeInstance(instance *models.Instance) error {
 	return k.DeleteInstance(instance)
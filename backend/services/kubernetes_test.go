@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Régression : les Services étaient créés avant la ligne en base et sélectionnaient
+// tous instance-id=0, donc les pods de n'importe quel joueur
+func TestServiceSelectorsAreUniquePerInstance(t *testing.T) {
+	setupTestDB(t)
+	k8sService, clientset := setupTestKubernetes(t)
+	ctx := context.Background()
+
+	first := createTestInstance(t, k8sService, 1)
+	second := createTestInstance(t, k8sService, 2)
+
+	for _, instance := range []struct {
+		podName, serviceName string
+		id                   int
+	}{
+		{first.PodName, first.ServiceName, first.ID},
+		{second.PodName, second.ServiceName, second.ID},
+	} {
+		if instance.id == 0 {
+			t.Fatalf("instance %s has no ID", instance.podName)
+		}
+
+		service, err := clientset.CoreV1().Services("ctf-test").Get(ctx, instance.serviceName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("get service %s: %v", instance.serviceName, err)
+		}
+		if got := service.Spec.Selector["instance-id"]; got != strconv.Itoa(instance.id) {
+			t.Errorf("service %s selects instance-id=%q, want %d", instance.serviceName, got, instance.id)
+		}
+
+		// Le Service ne doit sélectionner que les pods de son propre Deployment
+		selector := labels.SelectorFromSet(service.Spec.Selector)
+		deployments, err := clientset.AppsV1().Deployments("ctf-test").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("list deployments: %v", err)
+		}
+		for _, deployment := range deployments.Items {
+			matches := selector.Matches(labels.Set(deployment.Spec.Template.Labels))
+			if matches != (deployment.Name == instance.podName) {
+				t.Errorf("service %s matches pods of deployment %s: %t", instance.serviceName, deployment.Name, matches)
+			}
+		}
+	}
+}
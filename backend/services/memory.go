@@ -0,0 +1,111 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"sync"
+)
+
+// MemoryProvider simule les instances en mémoire, sans aucun conteneur.
+// Utilisé pour les tests et les démonstrations hors ligne.
+type MemoryProvider struct {
+	mu        sync.Mutex
+	nextPort  int
+	instances map[string]*memoryInstance
+}
+
+type memoryInstance struct {
+	status   string
+	reason   string
+	restarts int
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		nextPort:  30000,
+		instances: make(map[string]*memoryInstance),
+	}
+}
+
+func (m *MemoryProvider) CreateChallengeInstance(instance *models.Instance, challenge *models.Challenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	podName := fmt.Sprintf("mem-%d-%d-%d", instance.UserID, challenge.ID, instance.ID)
+	instance.PodName = podName
+	instance.ServiceName = fmt.Sprintf("svc-%s", podName)
+	instance.ExternalPort = m.nextPort
+	instance.InternalPort = challenge.Port
+	instance.AccessURL = fmt.Sprintf("http://memory.local:%d", m.nextPort)
+	flag, err := GenerateInstanceFlag(challenge.Flag, instance)
+	if err != nil {
+		return err
+	}
+	instance.Flag = flag
+	m.nextPort++
+
+	m.instances[podName] = &memoryInstance{status: StatusReady}
+	return nil
+}
+
+func (m *MemoryProvider) CleanupChallengeInstance(instance *models.Instance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.instances, instance.PodName)
+	return nil
+}
+
+func (m *MemoryProvider) RestartChallengeInstance(instance *models.Instance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mem, ok := m.instances[instance.PodName]
+	if !ok {
+		return fmt.Errorf("instance %s not found", instance.PodName)
+	}
+	mem.restarts++
+	mem.status, mem.reason = StatusReady, ""
+	return nil
+}
+
+func (m *MemoryProvider) ChallengeInstanceStatus(instance *models.Instance) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mem, ok := m.instances[instance.PodName]
+	if !ok {
+		return "", "", fmt.Errorf("instance %s not found", instance.PodName)
+	}
+	return mem.status, mem.reason, nil
+}
+
+func (m *MemoryProvider) ChallengeInstanceLogs(instance *models.Instance, tailLines int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mem, ok := m.instances[instance.PodName]
+	if !ok {
+		return "", fmt.Errorf("instance %s not found", instance.PodName)
+	}
+	return fmt.Sprintf("%s started (%d restarts)\n", instance.PodName, mem.restarts), nil
+}
+
+// SetStatus force le statut d'une instance simulée
+func (m *MemoryProvider) SetStatus(podName, status, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if mem, ok := m.instances[podName]; ok {
+		mem.status, mem.reason = status, reason
+	}
+}
+
+// Has indique si une instance simulée existe encore
+func (m *MemoryProvider) Has(podName string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.instances[podName]
+	return ok
+}
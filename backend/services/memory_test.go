@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReapExpiredWithMemoryProvider(t *testing.T) {
+	setupTestDB(t)
+	provider := NewMemoryProvider()
+
+	instance := createTestInstance(t, provider, 1)
+	if !provider.Has(instance.PodName) {
+		t.Fatalf("memory instance %s not created", instance.PodName)
+	}
+	if instance.Flag == "" {
+		t.Error("memory instance has no flag")
+	}
+	expireInstance(t, instance.ID)
+
+	if _, err := NewInstanceReaper(provider, time.Minute).ReapExpired(); err != nil {
+		t.Fatalf("ReapExpired: %v", err)
+	}
+	if provider.Has(instance.PodName) {
+		t.Errorf("memory instance %s still exists after reaping", instance.PodName)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"backend/db"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrPortCapacityExhausted est retourné quand tous les ports de la plage sont utilisés
+var ErrPortCapacityExhausted = errors.New("nodeport capacity exhausted")
+
+// PortAllocator attribue les NodePorts des instances sans collision, en tenant
+// compte des réservations en base et des Services existants du cluster
+// (ignorés si clientset est nil, pour les conteneurs locaux)
+type PortAllocator struct {
+	clientset kubernetes.Interface
+	min       int
+	max       int
+}
+
+// NewPortAllocator crée un allocateur sur la plage NODEPORT_RANGE (par défaut 30000-31999)
+func NewPortAllocator(clientset kubernetes.Interface) *PortAllocator {
+	min, max := 30000, 31999
+	if value := os.Getenv("NODEPORT_RANGE"); value != "" {
+		parsedMin, parsedMax, err := parsePortRange(value)
+		if err != nil {
+			log.Printf("Invalid NODEPORT_RANGE %q, using %d-%d: %v", value, min, max, err)
+		} else {
+			min, max = parsedMin, parsedMax
+		}
+	}
+
+	return &PortAllocator{
+		clientset: clientset,
+		min:       min,
+		max:       max,
+	}
+}
+
+func parsePortRange(value string) (int, int, error) {
+	bounds := strings.SplitN(value, "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("expected format min-max")
+	}
+
+	min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+	if err != nil {
+		return 0, 0, err
+	}
+	if min < 1 || max > 65535 || min > max {
+		return 0, 0, fmt.Errorf("invalid bounds %d-%d", min, max)
+	}
+	return min, max, nil
+}
+
+// Allocate réserve un port libre pour l'instance identifiée par podName
+func (p *PortAllocator) Allocate(ctx context.Context, podName string) (int, error) {
+	used, err := p.clusterNodePorts(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list cluster services: %v", err)
+	}
+
+	allocated, err := db.GetAllocatedPorts(p.min, p.max)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list allocated ports: %v", err)
+	}
+
+	for port := p.min; port <= p.max; port++ {
+		if used[port] || allocated[port] {
+			continue
+		}
+
+		// La réservation peut échouer si une autre requête a pris le port entre-temps
+		reserved, err := db.ReservePort(port, podName)
+		if err != nil {
+			return 0, fmt.Errorf("failed to reserve port %d: %v", port, err)
+		}
+		if reserved {
+			return port, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: no free port in range %d-%d", ErrPortCapacityExhausted, p.min, p.max)
+}
+
+// Release libère le port réservé par une instance
+func (p *PortAllocator) Release(podName string) error {
+	return db.ReleasePort(podName)
+}
+
+// clusterNodePorts liste les NodePorts déjà utilisés par les Services du cluster
+func (p *PortAllocator) clusterNodePorts(ctx context.Context) (map[int]bool, error) {
+	used := make(map[int]bool)
+	if p.clientset == nil {
+		return used, nil
+	}
+
+	services, err := p.clientset.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service := range services.Items {
+		for _, port := range service.Spec.Ports {
+			if port.NodePort != 0 {
+				used[int(port.NodePort)] = true
+			}
+		}
+	}
+	return used, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPortAllocatorSkipsUsedPorts(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("NODEPORT_RANGE", "30000-30003")
+
+	existing := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeNodePort,
+			Ports: []corev1.ServicePort{{Port: 80, NodePort: 30000}},
+		},
+	}
+	allocator := NewPortAllocator(fake.NewClientset(existing))
+	ctx := context.Background()
+
+	first, err := allocator.Allocate(ctx, "ctf-a")
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if first != 30001 {
+		t.Errorf("first port = %d, want 30001 (30000 is used by a cluster service)", first)
+	}
+
+	second, err := allocator.Allocate(ctx, "ctf-b")
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if second == first {
+		t.Errorf("port %d allocated twice", second)
+	}
+}
+
+func TestPortAllocatorCapacityExhausted(t *testing.T) {
+	setupTestDB(t)
+	t.Setenv("NODEPORT_RANGE", "30000-30001")
+
+	allocator := NewPortAllocator(fake.NewClientset())
+	ctx := context.Background()
+
+	for _, podName := range []string{"ctf-a", "ctf-b"} {
+		if _, err := allocator.Allocate(ctx, podName); err != nil {
+			t.Fatalf("Allocate(%s): %v", podName, err)
+		}
+	}
+
+	if _, err := allocator.Allocate(ctx, "ctf-c"); !errors.Is(err, ErrPortCapacityExhausted) {
+		t.Fatalf("Allocate on full range: err = %v, want ErrPortCapacityExhausted", err)
+	}
+
+	if err := allocator.Release("ctf-a"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	port, err := allocator.Allocate(ctx, "ctf-c")
+	if err != nil {
+		t.Fatalf("Allocate after release: %v", err)
+	}
+	if port != 30000 {
+		t.Errorf("port after release = %d, want 30000", port)
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidUnlock signale des conditions de déblocage refusées (prérequis inconnu,
+// d'un autre événement ou créant un cycle)
+var ErrInvalidUnlock = errors.New("invalid unlock conditions")
+
+// ValidateChallengeUnlock normalise les conditions de déblocage d'un challenge (mode
+// par défaut, prérequis dédoublonnés) et vérifie que ses prérequis existent et
+// relèvent du même événement
+func ValidateChallengeUnlock(challenge *models.Challenge, unlock *models.ChallengeUnlock) error {
+	if unlock.Mode == "" {
+		unlock.Mode = models.UnlockAll
+	}
+	if unlock.Mode != models.UnlockAll && unlock.Mode != models.UnlockAny {
+		return fmt.Errorf("%w: mode must be %q or %q", ErrInvalidUnlock, models.UnlockAll, models.UnlockAny)
+	}
+	if unlock.MinScore < 0 {
+		return fmt.Errorf("%w: min_score cannot be negative", ErrInvalidUnlock)
+	}
+
+	seen := make(map[int]bool)
+	prerequisites := []int{}
+	for _, id := range unlock.Prerequisites {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		prerequisites = append(prerequisites, id)
+	}
+	sort.Ints(prerequisites)
+	unlock.Prerequisites = prerequisites
+
+	for _, id := range prerequisites {
+		if id == challenge.ID {
+			return fmt.Errorf("%w: a challenge cannot be its own prerequisite", ErrInvalidUnlock)
+		}
+		prerequisite, err := db.GetChallengeIncludingInactive(id)
+		if err != nil {
+			return err
+		}
+		if prerequisite == nil {
+			return fmt.Errorf("%w: prerequisite %d not found", ErrInvalidUnlock, id)
+		}
+		if prerequisite.Scope() != challenge.Scope() {
+			return fmt.Errorf("%w: prerequisite %d belongs to another event", ErrInvalidUnlock, id)
+		}
+	}
+
+	return nil
+}
+
+// SetChallengeUnlock valide puis enregistre les conditions de déblocage d'un
+// challenge. L'absence de cycle est vérifiée dans la transaction qui les enregistre,
+// pour que deux modifications simultanées ne puissent pas en créer un.
+func SetChallengeUnlock(challenge *models.Challenge, unlock *models.ChallengeUnlock) error {
+	if err := ValidateChallengeUnlock(challenge, unlock); err != nil {
+		return err
+	}
+	return db.SetChallengeUnlock(challenge.ID, unlock, func(graph map[int][]int) error {
+		if cycle := prerequisiteCycle(graph, challenge.ID, unlock.Prerequisites); cycle != nil {
+			return fmt.Errorf("%w: prerequisites would create a cycle (%s)", ErrInvalidUnlock, formatCycle(cycle))
+		}
+		return nil
+	})
+}
+
+// prerequisiteCycle cherche le cycle que créerait le remplacement des prérequis de
+// challengeID dans graph. Retourne le chemin du cycle, de challengeID à lui-même, ou
+// nil s'il n'y en a pas.
+func prerequisiteCycle(graph map[int][]int, challengeID int, prerequisites []int) []int {
+	visited := make(map[int]bool)
+
+	var walk func(id int, path []int) []int
+	walk = func(id int, path []int) []int {
+		path = append(path, id)
+		if id == challengeID {
+			return path
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+		for _, next := range graph[id] {
+			if cycle := walk(next, path); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+
+	for _, id := range prerequisites {
+		if cycle := walk(id, []int{challengeID}); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func formatCycle(cycle []int) string {
+	parts := make([]string, len(cycle))
+	for i, id := range cycle {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// HideLocked indique si les challenges verrouillés doivent être masqués plutôt que
+// signalés comme verrouillés
+func HideLocked() (bool, error) {
+	settings, err := db.GetEventSettings()
+	if err != nil {
+		return false, err
+	}
+	return settings.LockedChallenges == models.LockedHidden, nil
+}
+
+// UnlockChecker évalue les conditions de déblocage des challenges pour un joueur, ou
+// pour son équipe sur les challenges d'un événement en mode équipe. Un visiteur
+// anonyme (userID 0) n'a rien résolu.
+type UnlockChecker struct {
+	userID  int
+	unlocks map[int]*models.ChallengeUnlock
+	scopes  map[int]*unlockScope // Par événement, 0 pour la plateforme
+}
+
+// unlockScope regroupe les résolutions et le score qui comptent dans un événement
+type unlockScope struct {
+	teamID *int // Équipe du joueur si l'événement est en mode équipe
+	solved map[int]bool
+	score  *int // Chargé à la première condition de score
+}
+
+// NewUnlockChecker charge les conditions de déblocage des challenges. Les
+// résolutions du joueur sont chargées à la demande, pour chaque événement.
+func NewUnlockChecker(userID int) (*UnlockChecker, error) {
+	unlocks, err := db.GetChallengeUnlocks()
+	if err != nil {
+		return nil, err
+	}
+
+	return &UnlockChecker{
+		userID:  userID,
+		unlocks: unlocks,
+		scopes:  map[int]*unlockScope{},
+	}, nil
+}
+
+// scope charge les résolutions du joueur, ou de son équipe si l'événement eventID est
+// en mode équipe
+func (u *UnlockChecker) scope(eventID int) (*unlockScope, error) {
+	if scope, ok := u.scopes[eventID]; ok {
+		return scope, nil
+	}
+
+	scope := &unlockScope{solved: map[int]bool{}}
+	if u.userID != 0 {
+		settings, err := db.GetScopedSettings(eventID)
+		if err != nil {
+			return nil, err
+		}
+		if settings.Mode == models.ModeTeam {
+			teamID, err := db.GetUserTeamID(u.userID)
+			if err != nil {
+				return nil, err
+			}
+			if teamID != 0 {
+				scope.teamID = &teamID
+			}
+		}
+
+		scope.solved, err = db.GetSolvedChallengeIDs(u.userID, scope.teamID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	u.scopes[eventID] = scope
+	return scope, nil
+}
+
+// Unlock retourne les conditions de déblocage d'un challenge, nil s'il n'en a pas
+func (u *UnlockChecker) Unlock(challengeID int) *models.ChallengeUnlock {
+	return u.unlocks[challengeID]
+}
+
+// Locked indique si un challenge de l'événement eventID (0 pour la plateforme) reste
+// verrouillé pour le joueur. Un challenge déjà résolu n'est jamais verrouillé.
+func (u *UnlockChecker) Locked(challengeID, eventID int) (bool, error) {
+	unlock := u.unlocks[challengeID]
+	if !unlock.HasConditions() {
+		return false, nil
+	}
+
+	scope, err := u.scope(eventID)
+	if err != nil {
+		return false, err
+	}
+	if scope.solved[challengeID] {
+		return false, nil
+	}
+	if !prerequisitesMet(unlock, scope.solved) {
+		return true, nil
+	}
+	if unlock.MinScore == 0 {
+		return false, nil
+	}
+
+	if scope.score == nil {
+		score := 0
+		if u.userID != 0 {
+			if score, err = db.GetScopedScore(eventID, u.userID, scope.teamID, nil); err != nil {
+				return false, err
+			}
+		}
+		scope.score = &score
+	}
+	return *scope.score < unlock.MinScore, nil
+}
+
+// prerequisitesMet vérifie les prérequis d'un challenge : tous doivent être résolus
+// en mode all, un seul suffit en mode any. Sans prérequis, la condition est remplie.
+func prerequisitesMet(unlock *models.ChallengeUnlock, solved map[int]bool) bool {
+	if len(unlock.Prerequisites) == 0 {
+		return true
+	}
+	for _, id := range unlock.Prerequisites {
+		if solved[id] && unlock.Mode == models.UnlockAny {
+			return true
+		}
+		if !solved[id] && unlock.Mode != models.UnlockAny {
+			return false
+		}
+	}
+	return unlock.Mode != models.UnlockAny
+}
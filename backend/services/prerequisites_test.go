@@ -0,0 +1,132 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPrerequisiteCycle(t *testing.T) {
+	// 3 nécessite 2, qui nécessite 1
+	graph := map[int][]int{2: {1}, 3: {2}}
+
+	if cycle := prerequisiteCycle(graph, 4, []int{3, 1}); cycle != nil {
+		t.Errorf("prerequisiteCycle(4 <- 3, 1) = %v, want no cycle", cycle)
+	}
+	if cycle := prerequisiteCycle(graph, 1, []int{3}); !reflect.DeepEqual(cycle, []int{1, 3, 2, 1}) {
+		t.Errorf("prerequisiteCycle(1 <- 3) = %v, want [1 3 2 1]", cycle)
+	}
+	// Les prérequis actuels du challenge modifié sont remplacés, pas ajoutés
+	if cycle := prerequisiteCycle(map[int][]int{1: {2}, 2: {3}}, 2, []int{}); cycle != nil {
+		t.Errorf("prerequisiteCycle(2 <- nothing) = %v, want no cycle", cycle)
+	}
+}
+
+func TestPrerequisitesMet(t *testing.T) {
+	solved := map[int]bool{1: true}
+	tests := []struct {
+		name   string
+		unlock models.ChallengeUnlock
+		want   bool
+	}{
+		{"no prerequisites", models.ChallengeUnlock{Mode: models.UnlockAll}, true},
+		{"all solved", models.ChallengeUnlock{Prerequisites: []int{1}, Mode: models.UnlockAll}, true},
+		{"all missing one", models.ChallengeUnlock{Prerequisites: []int{1, 2}, Mode: models.UnlockAll}, false},
+		{"any with one solved", models.ChallengeUnlock{Prerequisites: []int{1, 2}, Mode: models.UnlockAny}, true},
+		{"any with none solved", models.ChallengeUnlock{Prerequisites: []int{2, 3}, Mode: models.UnlockAny}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prerequisitesMet(&tt.unlock, solved); got != tt.want {
+				t.Errorf("prerequisitesMet(%+v) = %t, want %t", tt.unlock, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetChallengeUnlockRejectsCycle(t *testing.T) {
+	setupTestDB(t)
+
+	second, err := db.GetChallengeByID(2)
+	if err != nil || second == nil {
+		t.Fatalf("GetChallengeByID(2): %v", err)
+	}
+	unlock := &models.ChallengeUnlock{Prerequisites: []int{1, 1}}
+	if err := SetChallengeUnlock(second, unlock); err != nil {
+		t.Fatalf("SetChallengeUnlock(2 <- 1): %v", err)
+	}
+	if unlock.Mode != models.UnlockAll || !reflect.DeepEqual(unlock.Prerequisites, []int{1}) {
+		t.Errorf("normalised unlock = %+v, want mode all with prerequisites [1]", unlock)
+	}
+
+	first, err := db.GetChallengeByID(1)
+	if err != nil || first == nil {
+		t.Fatalf("GetChallengeByID(1): %v", err)
+	}
+	err = SetChallengeUnlock(first, &models.ChallengeUnlock{Prerequisites: []int{2}})
+	if !errors.Is(err, ErrInvalidUnlock) {
+		t.Errorf("SetChallengeUnlock(1 <- 2) = %v, want ErrInvalidUnlock", err)
+	}
+	err = SetChallengeUnlock(first, &models.ChallengeUnlock{Prerequisites: []int{99}})
+	if !errors.Is(err, ErrInvalidUnlock) {
+		t.Errorf("SetChallengeUnlock(1 <- 99) = %v, want ErrInvalidUnlock", err)
+	}
+
+	// Le cycle refusé n'a rien enregistré
+	stored, err := db.GetChallengeUnlock(1)
+	if err != nil {
+		t.Fatalf("GetChallengeUnlock(1): %v", err)
+	}
+	if len(stored.Prerequisites) != 0 {
+		t.Errorf("challenge 1 prerequisites = %v after a refused cycle, want none", stored.Prerequisites)
+	}
+}
+
+func TestUnlockCheckerPrerequisitesAndScore(t *testing.T) {
+	setupTestDB(t)
+	userID := createTestUser(t, "alice")
+
+	unlock := &models.ChallengeUnlock{Prerequisites: []int{1}, Mode: models.UnlockAll, MinScore: 100}
+	if err := db.SetChallengeUnlock(2, unlock, nil); err != nil {
+		t.Fatalf("SetChallengeUnlock: %v", err)
+	}
+
+	locked := func(userID int) bool {
+		t.Helper()
+		checker, err := NewUnlockChecker(userID)
+		if err != nil {
+			t.Fatalf("NewUnlockChecker: %v", err)
+		}
+		locked, err := checker.Locked(2, 0)
+		if err != nil {
+			t.Fatalf("Locked: %v", err)
+		}
+		return locked
+	}
+
+	if !locked(userID) {
+		t.Error("challenge 2 is unlocked before its prerequisite is solved")
+	}
+
+	submission := &models.Submission{UserID: userID, ChallengeID: 1, SubmittedFlag: "CTF{xss_reflected_pwned}", IsValid: true}
+	if _, err := db.RecordSubmission(submission); err != nil {
+		t.Fatalf("RecordSubmission: %v", err)
+	}
+	if !locked(userID) {
+		t.Error("challenge 2 is unlocked below its 100 points threshold")
+	}
+
+	unlock.MinScore = 50
+	if err := db.SetChallengeUnlock(2, unlock, nil); err != nil {
+		t.Fatalf("SetChallengeUnlock: %v", err)
+	}
+	if locked(userID) {
+		t.Error("challenge 2 is still locked with its prerequisite solved and 75 points")
+	}
+	if !locked(0) {
+		t.Error("challenge 2 is unlocked for an anonymous visitor")
+	}
+}
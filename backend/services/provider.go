@@ -0,0 +1,67 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// InstanceProvider est implémenté par chaque backend capable d'exécuter des
+// instances de challenge (Kubernetes, conteneurs locaux, mémoire pour les tests)
+type InstanceProvider interface {
+	// CreateChallengeInstance démarre une instance et renseigne PodName, ServiceName,
+	// ports, AccessURL et Flag
+	CreateChallengeInstance(instance *models.Instance, challenge *models.Challenge) error
+	// CleanupChallengeInstance supprime toutes les ressources de l'instance
+	CleanupChallengeInstance(instance *models.Instance) error
+	// RestartChallengeInstance recrée le conteneur de l'instance
+	RestartChallengeInstance(instance *models.Instance) error
+	// ChallengeInstanceStatus retourne le statut courant (creating, ready, failed, crashloop) et sa raison
+	ChallengeInstanceStatus(instance *models.Instance) (string, string, error)
+	// ChallengeInstanceLogs retourne les dernières lignes de logs de l'instance
+	ChallengeInstanceLogs(instance *models.Instance, tailLines int64) (string, error)
+}
+
+// Drivers d'instances disponibles
+const (
+	DriverKubernetes = "kubernetes"
+	DriverDocker     = "docker"
+	DriverMemory     = "memory"
+)
+
+// Provider est le backend d'instances sélectionné au démarrage
+var Provider InstanceProvider
+
+// InitInstanceProvider sélectionne le backend d'instances selon INSTANCE_DRIVER
+// (kubernetes par défaut, docker ou memory)
+func InitInstanceProvider() error {
+	driver := strings.ToLower(os.Getenv("INSTANCE_DRIVER"))
+	if driver == "" {
+		driver = DriverKubernetes
+	}
+
+	provider, err := NewInstanceProvider(driver)
+	if err != nil {
+		return err
+	}
+
+	Provider = provider
+	log.Printf("Instance driver: %s", driver)
+	return nil
+}
+
+// NewInstanceProvider construit le backend d'instances correspondant au driver demandé
+func NewInstanceProvider(driver string) (InstanceProvider, error) {
+	switch driver {
+	case DriverKubernetes:
+		return NewKubernetesService()
+	case DriverDocker:
+		return NewDockerProvider()
+	case DriverMemory:
+		return NewMemoryProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown instance driver %q", driver)
+	}
+}
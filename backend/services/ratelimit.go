@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter limite le nombre de requêtes par clé (utilisateur, IP...) avec un seau
+// à jetons en mémoire : limit requêtes en rafale, rechargées en continu sur window.
+type RateLimiter struct {
+	mu        sync.Mutex
+	burst     float64
+	rate      float64 // Jetons rechargés par seconde
+	window    time.Duration
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	rejected bool // Une requête a été refusée depuis la dernière acceptée
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		burst:   float64(limit),
+		rate:    float64(limit) / window.Seconds(),
+		window:  window,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow consomme un jeton pour key. Si le seau est vide, retourne false, le délai
+// avant le prochain jeton et true pour le premier refus depuis la dernière requête
+// acceptée, afin de ne signaler qu'une fois chaque dépassement.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.rejected = false
+		return true, 0, false
+	}
+
+	firstRejection := !bucket.rejected
+	bucket.rejected = true
+	retryAfter := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, retryAfter, firstRejection
+}
+
+// sweep supprime, au plus une fois par fenêtre, les seaux redevenus pleins
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimiterFromEnv crée un limiteur configuré par une variable de la forme
+// "10/1m" (10 requêtes par minute). Retourne nil si la limite vaut 0.
+func RateLimiterFromEnv(name, defaultValue string) *RateLimiter {
+	value := os.Getenv(name)
+	if value == "" {
+		value = defaultValue
+	}
+
+	limit, window, err := parseRate(value)
+	if err != nil {
+		log.Printf("Invalid %s %q (%v), using %s", name, value, err, defaultValue)
+		limit, window, _ = parseRate(defaultValue)
+	}
+	if limit == 0 {
+		log.Printf("%s disabled", name)
+		return nil
+	}
+	return NewRateLimiter(limit, window)
+}
+
+func parseRate(value string) (int, time.Duration, error) {
+	count, period, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, fmt.Errorf("expected <count>/<duration>")
+	}
+
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit < 0 {
+		return 0, 0, fmt.Errorf("invalid count %q", count)
+	}
+	window, err := time.ParseDuration(period)
+	if err != nil || window <= 0 {
+		return 0, 0, fmt.Errorf("invalid duration %q", period)
+	}
+	return limit, window, nil
+}
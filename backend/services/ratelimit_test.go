@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefillsOverWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(3, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := limiter.Allow("alice"); !allowed {
+			t.Fatalf("request %d rejected within burst", i+1)
+		}
+	}
+
+	allowed, retryAfter, first := limiter.Allow("alice")
+	if allowed || !first || retryAfter != 20*time.Second {
+		t.Fatalf("4th request: allowed = %t, retryAfter = %s, first = %t; want rejected after 20s, first", allowed, retryAfter, first)
+	}
+	if _, _, first := limiter.Allow("alice"); first {
+		t.Error("second rejection reported as first")
+	}
+	if allowed, _, _ := limiter.Allow("bob"); !allowed {
+		t.Error("limit shared between keys")
+	}
+
+	now = now.Add(20 * time.Second)
+	if allowed, _, _ := limiter.Allow("alice"); !allowed {
+		t.Error("request rejected after a token was refilled")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	limit, window, err := parseRate("30/1m")
+	if err != nil || limit != 30 || window != time.Minute {
+		t.Errorf("parseRate(30/1m) = %d, %s, %v", limit, window, err)
+	}
+	for _, value := range []string{"30", "x/1m", "30/soon", "30/0s"} {
+		if _, _, err := parseRate(value); err == nil {
+			t.Errorf("parseRate(%q) accepted", value)
+		}
+	}
+}
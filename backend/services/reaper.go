@@ -0,0 +1,69 @@
+package services
+
+import (
+	"backend/db"
+	"context"
+	"log"
+	"time"
+)
+
+// InstanceReaper supprime périodiquement les instances dont la date d'expiration est dépassée
+type InstanceReaper struct {
+	provider InstanceProvider
+	interval time.Duration
+}
+
+func NewInstanceReaper(provider InstanceProvider, interval time.Duration) *InstanceReaper {
+	return &InstanceReaper{
+		provider: provider,
+		interval: interval,
+	}
+}
+
+// Run exécute le nettoyage à chaque intervalle jusqu'à l'annulation du contexte
+func (r *InstanceReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	log.Printf("Instance reaper started (interval: %s)", r.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Instance reaper stopped")
+			return
+		case <-ticker.C:
+			if _, err := r.ReapExpired(); err != nil {
+				log.Printf("Instance reaper error: %v", err)
+			}
+		}
+	}
+}
+
+// ReapExpired supprime les ressources des instances expirées et
+// les marque comme expirées en base. Retourne le nombre d'instances traitées.
+func (r *InstanceReaper) ReapExpired() (int, error) {
+	instances, err := db.GetExpiredInstances(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for i := range instances {
+		instance := &instances[i]
+		if err := r.provider.CleanupChallengeInstance(instance); err != nil {
+			log.Printf("Failed to cleanup expired instance %d: %v", instance.ID, err)
+			continue
+		}
+
+		if err := db.UpdateInstanceStatus(instance.ID, "expired"); err != nil {
+			log.Printf("Failed to mark instance %d as expired: %v", instance.ID, err)
+			continue
+		}
+		reaped++
+	}
+
+	if reaped > 0 {
+		log.Printf("Instance reaper: %d expired instance(s) cleaned up", reaped)
+	}
+	return reaped, nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "ctf.sqlite"))
+	t.Setenv("FLAG_SECRET", "test-secret") // Requis pour créer des instances
+	db.InitDB()
+	t.Cleanup(db.CloseDB)
+}
+
+func createTestUser(t *testing.T, username string) int {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", PasswordHash: "x", Role: "user"}
+	if err := db.InsertUser(user); err != nil {
+		t.Fatalf("InsertUser(%s): %v", username, err)
+	}
+	return user.ID
+}
+
+func setupTestKubernetes(t *testing.T) (*KubernetesService, *fake.Clientset) {
+	t.Helper()
+	t.Setenv("CLUSTER_IP", "10.0.0.1")
+	clientset := fake.NewClientset()
+	k8sService, err := NewKubernetesServiceWithClient(clientset, "ctf-test")
+	if err != nil {
+		t.Fatalf("NewKubernetesServiceWithClient: %v", err)
+	}
+	return k8sService, clientset
+}
+
+// createTestInstance démarre une instance via le backend donné et l'enregistre en base
+func createTestInstance(t *testing.T, provider InstanceProvider, userID int) *models.Instance {
+	t.Helper()
+	challenge, err := db.GetChallengeByID(1)
+	if err != nil || challenge == nil {
+		t.Fatalf("GetChallengeByID: %v", err)
+	}
+
+	instance := &models.Instance{
+		UserID:      userID,
+		ChallengeID: challenge.ID,
+		Status:      "creating",
+	}
+	id, err := db.CreateInstance(instance)
+	if err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+	instance.ID = id
+
+	if err := provider.CreateChallengeInstance(instance, challenge); err != nil {
+		t.Fatalf("CreateChallengeInstance: %v", err)
+	}
+	if err := db.UpdateInstanceResources(instance); err != nil {
+		t.Fatalf("UpdateInstanceResources: %v", err)
+	}
+	return instance
+}
+
+func expireInstance(t *testing.T, id int) {
+	t.Helper()
+	_, err := db.DB.Exec("UPDATE instances SET expires_at = ? WHERE id = ?", time.Now().UTC().Add(-time.Minute), id)
+	if err != nil {
+		t.Fatalf("expire instance %d: %v", id, err)
+	}
+}
+
+func instanceStatus(t *testing.T, id int) string {
+	t.Helper()
+	instance, err := db.GetInstanceByID(id)
+	if err != nil || instance == nil {
+		t.Fatalf("GetInstanceByID(%d): %v", id, err)
+	}
+	return instance.Status
+}
+
+func TestReapExpiredCleansUpExpiredInstances(t *testing.T) {
+	setupTestDB(t)
+	k8sService, clientset := setupTestKubernetes(t)
+	ctx := context.Background()
+
+	expired := createTestInstance(t, k8sService, 1)
+	active := createTestInstance(t, k8sService, 2)
+	expireInstance(t, expired.ID)
+
+	reaped, err := NewInstanceReaper(k8sService, time.Minute).ReapExpired()
+	if err != nil {
+		t.Fatalf("ReapExpired: %v", err)
+	}
+	if reaped != 1 {
+		t.Fatalf("reaped = %d, want 1", reaped)
+	}
+
+	if _, err := clientset.AppsV1().Deployments("ctf-test").Get(ctx, expired.PodName, metav1.GetOptions{}); err == nil {
+		t.Errorf("deployment %s still exists after reaping", expired.PodName)
+	}
+	if _, err := clientset.CoreV1().Services("ctf-test").Get(ctx, expired.ServiceName, metav1.GetOptions{}); err == nil {
+		t.Errorf("service %s still exists after reaping", expired.ServiceName)
+	}
+	if status := instanceStatus(t, expired.ID); status != "expired" {
+		t.Errorf("expired instance status = %q, want %q", status, "expired")
+	}
+
+	if _, err := clientset.AppsV1().Deployments("ctf-test").Get(ctx, active.PodName, metav1.GetOptions{}); err != nil {
+		t.Errorf("active deployment %s was removed: %v", active.PodName, err)
+	}
+	if status := instanceStatus(t, active.ID); status == "expired" {
+		t.Errorf("active instance was marked expired")
+	}
+}
+
+func TestReapExpiredSkipsAlreadyReapedInstances(t *testing.T) {
+	setupTestDB(t)
+	k8sService, _ := setupTestKubernetes(t)
+
+	instance := createTestInstance(t, k8sService, 1)
+	expireInstance(t, instance.ID)
+
+	reaper := NewInstanceReaper(k8sService, time.Minute)
+	if _, err := reaper.ReapExpired(); err != nil {
+		t.Fatalf("first ReapExpired: %v", err)
+	}
+
+	reaped, err := reaper.ReapExpired()
+	if err != nil {
+		t.Fatalf("second ReapExpired: %v", err)
+	}
+	if reaped != 0 {
+		t.Errorf("second pass reaped %d instances, want 0", reaped)
+	}
+}
+
+func TestRunStopsWhenContextIsCancelled(t *testing.T) {
+	setupTestDB(t)
+	k8sService, _ := setupTestKubernetes(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewInstanceReaper(k8sService, 10*time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reaper did not stop after context cancellation")
+	}
+}
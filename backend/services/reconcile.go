@@ -0,0 +1,249 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reconcileGracePeriod laisse le temps à une création en cours d'enregistrer ses
+// ressources en base avant qu'elles ne soient considérées comme orphelines
+const reconcileGracePeriod = 2 * time.Minute
+
+// ReconcileReport décrit les écarts entre la table instances et le cluster
+type ReconcileReport struct {
+	DryRun            bool     `json:"dry_run"`
+	OrphanDeployments []string `json:"orphan_deployments"`
+	OrphanServices    []string `json:"orphan_services"`
+	OrphanIngresses   []string `json:"orphan_ingresses"`
+	LostInstances     []int    `json:"lost_instances"`
+	Errors            []string `json:"errors,omitempty"`
+}
+
+// RunReconciler réconcilie au démarrage puis à chaque intervalle jusqu'à l'annulation du contexte
+func (k *KubernetesService) RunReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Instance reconciler started (interval: %s)", interval)
+	for {
+		if _, err := k.Reconcile(false); err != nil {
+			log.Printf("Instance reconciler error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Instance reconciler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile compare les Deployments, Services et Ingresses labellisés app=ctf-challenge
+// des namespaces gérés avec les instances actives en base. Les ressources sans instance sont supprimées et
+// les instances sans Deployment marquées "lost". En mode dryRun, seul le rapport est produit.
+func (k *KubernetesService) Reconcile(dryRun bool) (*ReconcileReport, error) {
+	ctx := context.Background()
+	report := &ReconcileReport{
+		DryRun:            dryRun,
+		OrphanDeployments: []string{},
+		OrphanServices:    []string{},
+		OrphanIngresses:   []string{},
+		LostInstances:     []int{},
+	}
+
+	instances, err := db.GetActiveInstances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %v", err)
+	}
+	namespaces, err := k.managedNamespaces(instances)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %v", err)
+	}
+	managed := make(map[string]bool)
+	for _, namespace := range namespaces {
+		managed[namespace] = true
+	}
+
+	// Index des instances actives des namespaces gérés, par ID, Deployment et Service
+	// préfixés par leur namespace
+	knownIDs := make(map[string]bool)
+	knownDeployments := make(map[string]bool)
+	knownServices := make(map[string]bool)
+	knownIngresses := make(map[string]bool)
+	for _, instance := range instances {
+		if !managed[instance.Namespace] || instance.PodName == "" {
+			continue
+		}
+		knownIDs[instance.Namespace+"/"+strconv.Itoa(instance.ID)] = true
+		knownDeployments[instance.Namespace+"/"+instance.PodName] = true
+		knownServices[instance.Namespace+"/"+instance.ServiceName] = true
+		knownIngresses[instance.Namespace+"/"+ingressName(instance.PodName)] = true
+	}
+
+	listOptions := metav1.ListOptions{LabelSelector: "app=ctf-challenge"}
+	now := time.Now()
+	existingDeployments := make(map[string]bool)
+	for _, namespace := range namespaces {
+		isOrphan := func(meta metav1.ObjectMeta, known map[string]bool) bool {
+			if now.Sub(meta.CreationTimestamp.Time) < reconcileGracePeriod {
+				return false
+			}
+			if id := meta.Labels["instance-id"]; id != "" && id != "0" && knownIDs[namespace+"/"+id] {
+				return false
+			}
+			return !known[namespace+"/"+meta.Name]
+		}
+
+		deployments, err := k.clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments in %s: %v", namespace, err)
+		}
+		for _, deployment := range deployments.Items {
+			existingDeployments[namespace+"/"+deployment.Name] = true
+			if isOrphan(deployment.ObjectMeta, knownDeployments) {
+				report.OrphanDeployments = append(report.OrphanDeployments, k.resourceName(namespace, deployment.Name))
+			}
+		}
+
+		services, err := k.clientset.CoreV1().Services(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list services in %s: %v", namespace, err)
+		}
+		for _, service := range services.Items {
+			if isOrphan(service.ObjectMeta, knownServices) {
+				report.OrphanServices = append(report.OrphanServices, k.resourceName(namespace, service.Name))
+			}
+		}
+
+		ingresses, err := k.clientset.NetworkingV1().Ingresses(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ingresses in %s: %v", namespace, err)
+		}
+		for _, ingress := range ingresses.Items {
+			if isOrphan(ingress.ObjectMeta, knownIngresses) {
+				report.OrphanIngresses = append(report.OrphanIngresses, k.resourceName(namespace, ingress.Name))
+			}
+		}
+	}
+
+	// Une instance sans nom de pod a été enregistrée mais ses ressources n'ont jamais
+	// été créées (crash pendant la création) : elle est perdue elle aussi
+	for _, instance := range instances {
+		if instance.PodName != "" && !managed[instance.Namespace] {
+			continue
+		}
+		if now.Sub(instance.StartedAt) < reconcileGracePeriod {
+			continue
+		}
+		if instance.PodName == "" || !existingDeployments[instance.Namespace+"/"+instance.PodName] {
+			report.LostInstances = append(report.LostInstances, instance.ID)
+		}
+	}
+
+	if !dryRun {
+		k.applyReconcile(ctx, report, instances)
+	}
+
+	if len(report.OrphanDeployments)+len(report.OrphanServices)+len(report.OrphanIngresses)+len(report.LostInstances) > 0 {
+		log.Printf("Reconcile (dry run: %t): %d orphan deployment(s), %d orphan service(s), %d orphan ingress(es), %d lost instance(s)",
+			dryRun, len(report.OrphanDeployments), len(report.OrphanServices), len(report.OrphanIngresses), len(report.LostInstances))
+	}
+	return report, nil
+}
+
+// applyReconcile supprime les ressources orphelines et marque les instances perdues
+func (k *KubernetesService) applyReconcile(ctx context.Context, report *ReconcileReport, instances []models.Instance) {
+	addError := func(format string, args ...interface{}) {
+		message := fmt.Sprintf(format, args...)
+		log.Printf("Reconcile: %s", message)
+		report.Errors = append(report.Errors, message)
+	}
+
+	for _, resource := range report.OrphanIngresses {
+		namespace, name := k.splitResourceName(resource)
+		if err := k.clientset.NetworkingV1().Ingresses(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			addError("failed to delete ingress %s: %v", resource, err)
+		}
+	}
+
+	for _, resource := range report.OrphanServices {
+		namespace, name := k.splitResourceName(resource)
+		if err := k.clientset.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			addError("failed to delete service %s: %v", resource, err)
+		}
+	}
+
+	for _, resource := range report.OrphanDeployments {
+		namespace, name := k.splitResourceName(resource)
+		if err := k.deleteDeployment(ctx, namespace, name); err != nil {
+			addError("failed to delete deployment %s: %v", resource, err)
+			continue
+		}
+		k.releasePort(name)
+	}
+
+	podNames := make(map[int]string)
+	for _, instance := range instances {
+		podNames[instance.ID] = instance.PodName
+	}
+	for _, id := range report.LostInstances {
+		if err := db.UpdateInstanceStatus(id, "lost"); err != nil {
+			addError("failed to mark instance %d as lost: %v", id, err)
+			continue
+		}
+		k.releasePort(podNames[id])
+	}
+}
+
+// managedNamespaces retourne les namespaces à réconcilier : le namespace par défaut,
+// ceux des événements hébergés et ceux des instances actives
+func (k *KubernetesService) managedNamespaces(instances []models.Instance) ([]string, error) {
+	events, err := db.GetEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{k.namespace: true}
+	namespaces := []string{k.namespace}
+	add := func(namespace string) {
+		if namespace != "" && !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	for _, event := range events {
+		add(event.Namespace)
+	}
+	for _, instance := range instances {
+		if instance.PodName != "" {
+			add(instance.Namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+// resourceName désigne une ressource dans le rapport : son nom seul dans le namespace
+// par défaut, préfixé par son namespace ailleurs
+func (k *KubernetesService) resourceName(namespace, name string) string {
+	if namespace == k.namespace {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// splitResourceName retrouve le namespace et le nom d'une ressource du rapport
+func (k *KubernetesService) splitResourceName(resource string) (string, string) {
+	if namespace, name, found := strings.Cut(resource, "/"); found {
+		return namespace, name
+	}
+	return k.namespace, resource
+}
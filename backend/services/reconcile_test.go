@@ -0,0 +1,139 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func ageInstance(t *testing.T, id int) {
+	t.Helper()
+	_, err := db.DB.Exec("UPDATE instances SET started_at = ? WHERE id = ?", time.Now().UTC().Add(-time.Hour), id)
+	if err != nil {
+		t.Fatalf("age instance %d: %v", id, err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	setupTestDB(t)
+	k8sService, clientset := setupTestKubernetes(t)
+	ctx := context.Background()
+
+	tracked := createTestInstance(t, k8sService, 1)
+	lost := createTestInstance(t, k8sService, 2)
+	ageInstance(t, tracked.ID)
+	ageInstance(t, lost.ID)
+
+	// Ressources du pod "perdu" supprimées hors de la plateforme
+	if err := k8sService.deleteDeployment(ctx, "ctf-test", lost.PodName); err != nil {
+		t.Fatalf("delete deployment: %v", err)
+	}
+
+	orphan := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ctf-orphan",
+			Namespace: "ctf-test",
+			Labels:    map[string]string{"app": "ctf-challenge", "instance-id": "0"},
+		},
+	}
+	if _, err := clientset.AppsV1().Deployments("ctf-test").Create(ctx, orphan, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create orphan deployment: %v", err)
+	}
+
+	report, err := k8sService.Reconcile(true)
+	if err != nil {
+		t.Fatalf("Reconcile(dry run): %v", err)
+	}
+	if len(report.OrphanDeployments) != 1 || report.OrphanDeployments[0] != "ctf-orphan" {
+		t.Errorf("orphan deployments = %v, want [ctf-orphan]", report.OrphanDeployments)
+	}
+	if len(report.LostInstances) != 1 || report.LostInstances[0] != lost.ID {
+		t.Errorf("lost instances = %v, want [%d]", report.LostInstances, lost.ID)
+	}
+	if _, err := clientset.AppsV1().Deployments("ctf-test").Get(ctx, "ctf-orphan", metav1.GetOptions{}); err != nil {
+		t.Errorf("dry run deleted the orphan deployment: %v", err)
+	}
+	if status := instanceStatus(t, lost.ID); status == "lost" {
+		t.Error("dry run marked the instance as lost")
+	}
+
+	if _, err := k8sService.Reconcile(false); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if _, err := clientset.AppsV1().Deployments("ctf-test").Get(ctx, "ctf-orphan", metav1.GetOptions{}); err == nil {
+		t.Error("orphan deployment still exists after reconcile")
+	}
+	if status := instanceStatus(t, lost.ID); status != "lost" {
+		t.Errorf("lost instance status = %q, want %q", status, "lost")
+	}
+	if _, err := clientset.AppsV1().Deployments("ctf-test").Get(ctx, tracked.PodName, metav1.GetOptions{}); err != nil {
+		t.Errorf("tracked deployment was removed: %v", err)
+	}
+	if status := instanceStatus(t, tracked.ID); status == "lost" {
+		t.Error("tracked instance was marked lost")
+	}
+}
+
+func TestReconcileEventNamespace(t *testing.T) {
+	setupTestDB(t)
+	k8sService, clientset := setupTestKubernetes(t)
+	ctx := context.Background()
+
+	event := &models.Event{Slug: "weekly", Name: "Weekly", Namespace: "ctf-weekly",
+		StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)}
+	if err := db.CreateEvent(event); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+
+	challenge, _ := db.GetChallengeByID(1)
+	instance := &models.Instance{UserID: 1, ChallengeID: challenge.ID, Status: "creating", Namespace: event.Namespace}
+	id, err := db.CreateInstance(instance)
+	if err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+	instance.ID = id
+	if err := k8sService.CreateChallengeInstance(instance, challenge); err != nil {
+		t.Fatalf("CreateChallengeInstance: %v", err)
+	}
+	if err := db.UpdateInstanceResources(instance); err != nil {
+		t.Fatalf("UpdateInstanceResources: %v", err)
+	}
+	ageInstance(t, instance.ID)
+
+	if _, err := clientset.CoreV1().Namespaces().Get(ctx, "ctf-weekly", metav1.GetOptions{}); err != nil {
+		t.Fatalf("event namespace not created: %v", err)
+	}
+	if _, err := clientset.AppsV1().Deployments("ctf-weekly").Get(ctx, instance.PodName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("deployment not created in the event namespace: %v", err)
+	}
+
+	orphan := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ctf-orphan",
+			Namespace: "ctf-weekly",
+			Labels:    map[string]string{"app": "ctf-challenge", "instance-id": "0"},
+		},
+	}
+	if _, err := clientset.AppsV1().Deployments("ctf-weekly").Create(ctx, orphan, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create orphan deployment: %v", err)
+	}
+
+	report, err := k8sService.Reconcile(false)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(report.OrphanDeployments) != 1 || report.OrphanDeployments[0] != "ctf-weekly/ctf-orphan" {
+		t.Errorf("orphan deployments = %v, want [ctf-weekly/ctf-orphan]", report.OrphanDeployments)
+	}
+	if len(report.LostInstances) != 0 {
+		t.Errorf("lost instances = %v, want none", report.LostInstances)
+	}
+	if _, err := clientset.AppsV1().Deployments("ctf-weekly").Get(ctx, "ctf-orphan", metav1.GetOptions{}); err == nil {
+		t.Error("orphan deployment still exists after reconcile")
+	}
+}
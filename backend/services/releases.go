@@ -0,0 +1,95 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ChallengeVisible indique si un challenge est publié à la date now
+func ChallengeVisible(challenge *models.Challenge, now time.Time) bool {
+	if challenge.VisibleFrom != nil && now.Before(*challenge.VisibleFrom) {
+		return false
+	}
+	return challenge.VisibleUntil == nil || now.Before(*challenge.VisibleUntil)
+}
+
+// ValidateChallengeSchedule vérifie qu'une fenêtre de publication n'est pas vide
+func ValidateChallengeSchedule(visibleFrom, visibleUntil *time.Time) error {
+	if visibleFrom != nil && visibleUntil != nil && !visibleUntil.After(*visibleFrom) {
+		return fmt.Errorf("visible_until must be after visible_from")
+	}
+	return nil
+}
+
+// ReleaseAnnouncer annonce périodiquement les challenges dont la publication
+// programmée vient d'avoir lieu
+type ReleaseAnnouncer struct {
+	interval time.Duration
+}
+
+func NewReleaseAnnouncer(interval time.Duration) *ReleaseAnnouncer {
+	return &ReleaseAnnouncer{interval: interval}
+}
+
+// Run vérifie les publications à chaque intervalle jusqu'à l'annulation du contexte
+func (r *ReleaseAnnouncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	log.Printf("Release announcer started (interval: %s)", r.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Release announcer stopped")
+			return
+		case <-ticker.C:
+			if _, err := r.AnnounceReleases(time.Now()); err != nil {
+				log.Printf("Release announcer error: %v", err)
+			}
+		}
+	}
+}
+
+// AnnounceReleases publie une annonce pour chaque challenge devenu visible à la date
+// now et pas encore annoncé, une fois son événement commencé. Retourne le nombre
+// d'annonces publiées.
+func (r *ReleaseAnnouncer) AnnounceReleases(now time.Time) (int, error) {
+	challenges, err := db.GetPendingReleases(now)
+	if err != nil {
+		return 0, err
+	}
+
+	announced := 0
+	for i := range challenges {
+		challenge := &challenges[i]
+		marked, err := db.MarkReleaseAnnounced(challenge.ID)
+		if err != nil {
+			log.Printf("Failed to mark release of challenge %d: %v", challenge.ID, err)
+			continue
+		}
+		if !marked {
+			continue
+		}
+
+		announcement := &models.Announcement{
+			Type:        models.AnnouncementChallengeReleased,
+			Message:     fmt.Sprintf("Nouveau challenge disponible : %s (%s, %d points)", challenge.Name, challenge.Category, challenge.Points),
+			ChallengeID: &challenge.ID,
+			EventID:     challenge.EventID,
+		}
+		if err := db.CreateAnnouncement(announcement); err != nil {
+			log.Printf("Failed to announce release of challenge %d: %v", challenge.ID, err)
+			continue
+		}
+		announced++
+	}
+
+	if announced > 0 {
+		log.Printf("Release announcer: %d challenge(s) released", announced)
+	}
+	return announced, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"testing"
+	"time"
+)
+
+func TestChallengeVisible(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		challenge models.Challenge
+		want      bool
+	}{
+		{"unscheduled", models.Challenge{}, true},
+		{"released", models.Challenge{VisibleFrom: &past}, true},
+		{"not yet released", models.Challenge{VisibleFrom: &future}, false},
+		{"withdrawn", models.Challenge{VisibleUntil: &past}, false},
+		{"within window", models.Challenge{VisibleFrom: &past, VisibleUntil: &future}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChallengeVisible(&tt.challenge, now); got != tt.want {
+				t.Errorf("ChallengeVisible() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnounceReleasesOnce(t *testing.T) {
+	setupTestDB(t)
+	now := time.Now()
+	releaseAt := now.Add(time.Hour)
+
+	if err := db.SetChallengeSchedule(2, &releaseAt, nil); err != nil {
+		t.Fatalf("SetChallengeSchedule: %v", err)
+	}
+
+	challenges, err := db.GetAllChallenges(0)
+	if err != nil {
+		t.Fatalf("GetAllChallenges: %v", err)
+	}
+	for _, challenge := range challenges {
+		if challenge.ID == 2 {
+			t.Error("GetAllChallenges lists challenge 2 before its release")
+		}
+	}
+
+	upcoming, err := db.GetUpcomingReleases(now)
+	if err != nil {
+		t.Fatalf("GetUpcomingReleases: %v", err)
+	}
+	if len(upcoming) != 1 || upcoming[0].ID != 2 {
+		t.Fatalf("GetUpcomingReleases = %+v, want challenge 2", upcoming)
+	}
+
+	announcer := NewReleaseAnnouncer(time.Minute)
+	if count, err := announcer.AnnounceReleases(now); err != nil || count != 0 {
+		t.Fatalf("AnnounceReleases before release = %d, %v, want 0", count, err)
+	}
+	if count, err := announcer.AnnounceReleases(releaseAt.Add(time.Minute)); err != nil || count != 1 {
+		t.Fatalf("AnnounceReleases after release = %d, %v, want 1", count, err)
+	}
+	if count, err := announcer.AnnounceReleases(releaseAt.Add(2 * time.Minute)); err != nil || count != 0 {
+		t.Fatalf("AnnounceReleases again = %d, %v, want 0", count, err)
+	}
+
+	announcements, err := db.GetAnnouncements(10)
+	if err != nil {
+		t.Fatalf("GetAnnouncements: %v", err)
+	}
+	if len(announcements) != 1 || announcements[0].Type != models.AnnouncementChallengeReleased ||
+		announcements[0].ChallengeID == nil || *announcements[0].ChallengeID != 2 {
+		t.Errorf("announcements = %+v, want one release of challenge 2", announcements)
+	}
+}
+
+func TestAnnounceReleasesWaitsForEventStart(t *testing.T) {
+	setupTestDB(t)
+	now := time.Now()
+	releaseAt, startAt := now.Add(time.Hour), now.Add(2*time.Hour)
+
+	event := &models.Event{Slug: "yearly", Name: "Yearly", Namespace: "ctf-yearly",
+		StartTime: startAt, EndTime: startAt.Add(24 * time.Hour)}
+	if err := db.CreateEvent(event); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	challengeID, err := db.CreateChallenge(&models.Challenge{Name: "Yearly pwn", Description: "d", Category: "Pwn",
+		Difficulty: "hard", Points: 300, Flag: "CTF{yearly}", ScoringType: models.ScoringStatic, IsActive: true, EventID: &event.ID})
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+	if err := db.SetChallengeSchedule(challengeID, &releaseAt, nil); err != nil {
+		t.Fatalf("SetChallengeSchedule: %v", err)
+	}
+
+	// Publié avant l'ouverture de l'événement : l'annonce attend son début
+	announcer := NewReleaseAnnouncer(time.Minute)
+	if count, err := announcer.AnnounceReleases(releaseAt.Add(time.Minute)); err != nil || count != 0 {
+		t.Fatalf("AnnounceReleases before the event starts = %d, %v, want 0", count, err)
+	}
+	if count, err := announcer.AnnounceReleases(startAt.Add(time.Minute)); err != nil || count != 1 {
+		t.Fatalf("AnnounceReleases once the event started = %d, %v, want 1", count, err)
+	}
+
+	announcements, err := db.GetAnnouncements(10)
+	if err != nil {
+		t.Fatalf("GetAnnouncements: %v", err)
+	}
+	if len(announcements) != 1 || announcements[0].EventID == nil || *announcements[0].EventID != event.ID {
+		t.Errorf("announcements = %+v, want one release in event %d", announcements, event.ID)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"backend/db"
+	"backend/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Statuts d'instance dérivés de l'état du cluster
+const (
+	StatusCreating  = "creating"
+	StatusReady     = "ready"
+	StatusFailed    = "failed"
+	StatusCrashLoop = "crashloop"
+)
+
+// Raisons d'attente d'un conteneur qui indiquent un échec définitif du démarrage
+var failedWaitingReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// WatchInstanceStatus surveille les Deployments et Pods des challenges et reporte
+// leur état (creating, ready, failed, crashloop) sur les instances en base,
+// jusqu'à l'annulation du contexte. Tous les namespaces sont surveillés, les
+// événements hébergés ayant chacun le leur.
+func (k *KubernetesService) WatchInstanceStatus(ctx context.Context) {
+	factory := informers.NewSharedInformerFactoryWithOptions(k.clientset, 5*time.Minute,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = "app=ctf-challenge"
+		}))
+
+	podInformer := factory.Core().V1().Pods().Informer()
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { k.handlePodEvent(obj) },
+		UpdateFunc: func(_, obj interface{}) { k.handlePodEvent(obj) },
+	})
+
+	deploymentInformer := factory.Apps().V1().Deployments().Informer()
+	deploymentInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { k.handleDeploymentEvent(obj) },
+		UpdateFunc: func(_, obj interface{}) { k.handleDeploymentEvent(obj) },
+	})
+
+	log.Println("Watching challenge instances in all namespaces")
+	factory.Start(ctx.Done())
+	<-ctx.Done()
+	factory.Shutdown()
+	log.Println("Instance status watcher stopped")
+}
+
+func (k *KubernetesService) handlePodEvent(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+
+	deploymentName := pod.Labels["pod-name"]
+	if deploymentName == "" {
+		return
+	}
+
+	status, reason := podInstanceStatus(pod)
+	updateInstanceStatus(deploymentName, status, reason)
+}
+
+func (k *KubernetesService) handleDeploymentEvent(obj interface{}) {
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return
+	}
+
+	status, reason := deploymentInstanceStatus(deployment)
+	if status == "" {
+		return
+	}
+	updateInstanceStatus(deployment.Name, status, reason)
+}
+
+func updateInstanceStatus(podName, status, reason string) {
+	updated, err := db.UpdateInstanceStatusByPodName(podName, status, reason)
+	if err != nil {
+		log.Printf("Failed to update status of instance %s: %v", podName, err)
+		return
+	}
+	if updated {
+		log.Printf("Instance %s is now %s %s", podName, status, reason)
+	}
+}
+
+// podInstanceStatus déduit le statut d'une instance de l'état de son pod
+func podInstanceStatus(pod *corev1.Pod) (string, string) {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+		reason := cs.State.Waiting.Reason
+		if reason == "CrashLoopBackOff" {
+			return StatusCrashLoop, reason
+		}
+		if failedWaitingReasons[reason] {
+			return StatusFailed, reason
+		}
+	}
+
+	if pod.Status.Phase == corev1.PodFailed {
+		return StatusFailed, pod.Status.Reason
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+			return StatusReady, ""
+		}
+	}
+
+	return StatusCreating, ""
+}
+
+// deploymentInstanceStatus déduit le statut d'une instance des conditions de son
+// Deployment. Retourne un statut vide si le Deployment n'apporte pas d'information
+// au-delà de celle de ses pods.
+func deploymentInstanceStatus(deployment *appsv1.Deployment) (string, string) {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing &&
+			condition.Status == corev1.ConditionFalse &&
+			condition.Reason == "ProgressDeadlineExceeded" {
+			return StatusFailed, condition.Reason
+		}
+	}
+	return "", ""
+}
+
+// ChallengeInstanceStatus lit le statut courant d'une instance à partir de son pod
+func (k *KubernetesService) ChallengeInstanceStatus(instance *models.Instance) (string, string, error) {
+	ctx := context.Background()
+
+	deployment, err := k.clientset.AppsV1().Deployments(k.instanceNamespace(instance)).Get(ctx, instance.PodName, metav1.GetOptions{})
+	if err != nil {
+		return "", "", err
+	}
+	if status, reason := deploymentInstanceStatus(deployment); status != "" {
+		return status, reason, nil
+	}
+
+	pods, err := k.instancePods(ctx, instance)
+	if err != nil {
+		return "", "", err
+	}
+	if len(pods) == 0 {
+		return StatusCreating, "", nil
+	}
+
+	status, reason := podInstanceStatus(&pods[0])
+	return status, reason, nil
+}
+
+// ChallengeInstanceLogs retourne les derniers logs du pod d'une instance
+func (k *KubernetesService) ChallengeInstanceLogs(instance *models.Instance, tailLines int64) (string, error) {
+	ctx := context.Background()
+
+	pods, err := k.instancePods(ctx, instance)
+	if err != nil {
+		return "", err
+	}
+	if len(pods) == 0 {
+		return "", fmt.Errorf("no pod found for instance %d", instance.ID)
+	}
+
+	logs, err := k.clientset.CoreV1().Pods(k.instanceNamespace(instance)).GetLogs(pods[0].Name, &corev1.PodLogOptions{
+		TailLines: &tailLines,
+	}).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(logs), nil
+}
+
+func (k *KubernetesService) instancePods(ctx context.Context, instance *models.Instance) ([]corev1.Pod, error) {
+	pods, err := k.clientset.CoreV1().Pods(k.instanceNamespace(instance)).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=ctf-challenge,pod-name=" + instance.PodName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func waitingPod(reason string) *corev1.Pod {
+	return &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}},
+			},
+		},
+	}
+}
+
+func TestPodInstanceStatus(t *testing.T) {
+	readyPod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		pod        *corev1.Pod
+		wantStatus string
+		wantReason string
+	}{
+		{"pending", &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}}, StatusCreating, ""},
+		{"pulling", waitingPod("ContainerCreating"), StatusCreating, ""},
+		{"image pull backoff", waitingPod("ImagePullBackOff"), StatusFailed, "ImagePullBackOff"},
+		{"crash loop", waitingPod("CrashLoopBackOff"), StatusCrashLoop, "CrashLoopBackOff"},
+		{"ready", readyPod, StatusReady, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, reason := podInstanceStatus(tt.pod)
+			if status != tt.wantStatus || reason != tt.wantReason {
+				t.Errorf("podInstanceStatus() = (%q, %q), want (%q, %q)", status, reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestWatchInstanceStatusUpdatesInstance(t *testing.T) {
+	setupTestDB(t)
+	k8sService, clientset := setupTestKubernetes(t)
+	instance := createTestInstance(t, k8sService, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		k8sService.WatchInstanceStatus(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	pod := waitingPod("ImagePullBackOff")
+	pod.ObjectMeta = metav1.ObjectMeta{
+		Name:      instance.PodName + "-abc12",
+		Namespace: "ctf-test",
+		Labels:    map[string]string{"app": "ctf-challenge", "pod-name": instance.PodName},
+	}
+	if _, err := clientset.CoreV1().Pods("ctf-test").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create pod: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if instanceStatus(t, instance.ID) == StatusFailed {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("instance status = %q, want %q", instanceStatus(t, instance.ID), StatusFailed)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStorage stocke les pièces jointes des challenges. Les clés sont des chemins
+// relatifs (ex: 3/9f2c..-binary.elf) choisis par l'appelant.
+type FileStorage interface {
+	// Save écrit le contenu sous la clé donnée et retourne sa taille et son SHA-256 (hex)
+	Save(key string, content io.Reader) (int64, string, error)
+	// Open ouvre le contenu stocké sous la clé donnée
+	Open(key string) (io.ReadCloser, error)
+	// Delete supprime le contenu stocké sous la clé donnée
+	Delete(key string) error
+}
+
+// Storage est le stockage des pièces jointes initialisé au démarrage
+var Storage FileStorage
+
+// InitStorage initialise le stockage local des pièces jointes dans ATTACHMENTS_DIR
+// (par défaut ./attachments)
+func InitStorage() error {
+	root := os.Getenv("ATTACHMENTS_DIR")
+	if root == "" {
+		root = "./attachments"
+	}
+
+	storage, err := NewLocalStorage(root)
+	if err != nil {
+		return err
+	}
+
+	Storage = storage
+	log.Printf("Attachments stored in %s", root)
+	return nil
+}
+
+// LocalStorage stocke les fichiers sur le disque local, sous un répertoire racine
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory %s: %v", root, err)
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+func (s *LocalStorage) Save(key string, content io.Reader) (int64, string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, "", err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return 0, "", err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(file, io.TeeReader(content, hash))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path résout une clé sous la racine en refusant toute sortie du répertoire
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if clean == "." || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, clean), nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStorageSaveComputesChecksum(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	content := "MZ\x90\x00 not really a binary"
+	size, checksum, err := storage.Save("1/abc-crackme.exe", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	if checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("checksum = %s, want %x", checksum, sum)
+	}
+	if size != int64(len(content)) {
+		t.Errorf("size = %d, want %d", size, len(content))
+	}
+
+	file, err := storage.Open("1/abc-crackme.exe")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer file.Close()
+	read, _ := io.ReadAll(file)
+	if string(read) != content {
+		t.Errorf("read back %q, want %q", read, content)
+	}
+}
+
+func TestLocalStorageRejectsPathTraversal(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	for _, key := range []string{"../escape", "/etc/passwd", "1/../../escape"} {
+		if _, _, err := storage.Save(key, strings.NewReader("x")); err == nil {
+			t.Errorf("Save(%q) succeeded, want error", key)
+		}
+	}
+}
+
+func TestAttachmentSignature(t *testing.T) {
+	t.Setenv("ATTACHMENT_SECRET", "test-secret")
+
+	expires := time.Now().Add(time.Minute).Unix()
+	sig, err := attachmentSignature(4, 7, expires)
+	if err != nil {
+		t.Fatalf("attachmentSignature: %v", err)
+	}
+
+	if !VerifyAttachmentSignature(4, 7, expires, sig) {
+		t.Error("valid signature rejected")
+	}
+	if VerifyAttachmentSignature(5, 7, expires, sig) {
+		t.Error("signature accepted for another attachment")
+	}
+	if VerifyAttachmentSignature(4, 8, expires, sig) {
+		t.Error("signature accepted for another user")
+	}
+
+	expired := time.Now().Add(-time.Minute).Unix()
+	expiredSig, _ := attachmentSignature(4, 7, expired)
+	if VerifyAttachmentSignature(4, 7, expired, expiredSig) {
+		t.Error("expired signature accepted")
+	}
+
+	// Sans secret, rien n'est signé ni accepté, même avec JWT_SECRET défini
+	t.Setenv("ATTACHMENT_SECRET", "")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	if _, _, err := SignAttachmentURL(4, 7); !errors.Is(err, ErrAttachmentSecretMissing) {
+		t.Errorf("SignAttachmentURL without ATTACHMENT_SECRET = %v, want ErrAttachmentSecretMissing", err)
+	}
+	if VerifyAttachmentSignature(4, 7, expires, sig) {
+		t.Error("signature accepted without ATTACHMENT_SECRET")
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"backend/models"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var (
+	// Référence d'image Docker : [registre[:port]/]chemin[:tag][@sha256:digest]
+	imageReferencePattern = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*(:[0-9]+)?(/[a-z0-9]+([._-]+[a-z0-9]+)*)*(:[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(@sha256:[a-f0-9]{64})?$`)
+	// Flag au format PREFIXE{contenu}, requis pour dériver les flags d'instance
+	flagPattern = regexp.MustCompile(`^[A-Za-z0-9_]+\{[^\s{}]+\}$`)
+)
+
+const (
+	minTimeLimit = 60
+	maxTimeLimit = 7 * 24 * 3600
+)
+
+// ValidateChallenge vérifie la difficulté (ramenée en minuscules), l'image, le port,
+// les limites de ressources, la durée de vie et le flag d'un challenge avant son
+// enregistrement. Un challenge sans image
+// (pièces jointes uniquement) n'est pas soumis aux contrôles propres aux instances.
+// Les limites maximales sont configurables avec CHALLENGE_MAX_CPU (par défaut 2) et
+// CHALLENGE_MAX_MEMORY (par défaut 2Gi).
+func ValidateChallenge(challenge *models.Challenge) error {
+	if strings.TrimSpace(challenge.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(challenge.Flag) > 255 || !flagPattern.MatchString(challenge.Flag) {
+		return fmt.Errorf("flag must match PREFIX{content} without whitespace (max 255 characters)")
+	}
+	challenge.Difficulty = strings.ToLower(strings.TrimSpace(challenge.Difficulty))
+	switch challenge.Difficulty {
+	case "easy", "medium", "hard":
+	default:
+		return fmt.Errorf("difficulty must be easy, medium or hard")
+	}
+	if challenge.Points < 1 {
+		return fmt.Errorf("points must be positive")
+	}
+	if challenge.ScoringType == models.ScoringDynamic && challenge.MinimumValue > challenge.InitialValue {
+		return fmt.Errorf("minimum_value cannot exceed initial value")
+	}
+
+	if challenge.DockerImage == "" {
+		return nil
+	}
+
+	if len(challenge.DockerImage) > 255 || !imageReferencePattern.MatchString(challenge.DockerImage) {
+		return fmt.Errorf("invalid docker image reference %q", challenge.DockerImage)
+	}
+	if challenge.Port < 1 || challenge.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if err := validateQuantity("cpu_limit", challenge.CPULimit, limitFromEnv("CHALLENGE_MAX_CPU", "2")); err != nil {
+		return err
+	}
+	if err := validateQuantity("memory_limit", challenge.MemoryLimit, limitFromEnv("CHALLENGE_MAX_MEMORY", "2Gi")); err != nil {
+		return err
+	}
+	if challenge.TimeLimit < minTimeLimit || challenge.TimeLimit > maxTimeLimit {
+		return fmt.Errorf("time_limit must be between %d and %d seconds", minTimeLimit, maxTimeLimit)
+	}
+	return nil
+}
+
+// ApplyChallengeDefaults complète les limites de ressources et la durée de vie non renseignées
+func ApplyChallengeDefaults(challenge *models.Challenge) {
+	if challenge.CPULimit == "" {
+		challenge.CPULimit = "0.5"
+	}
+	if challenge.MemoryLimit == "" {
+		challenge.MemoryLimit = "512Mi"
+	}
+	if challenge.TimeLimit == 0 {
+		challenge.TimeLimit = 3600
+	}
+}
+
+// IsFileCategory indique si une catégorie accepte des challenges sans instance,
+// distribués uniquement sous forme de pièces jointes
+func IsFileCategory(category string) bool {
+	switch strings.ToLower(category) {
+	case "reverse", "forensics":
+		return true
+	}
+	return false
+}
+
+// validateQuantity vérifie qu'une limite de ressource est une quantité Kubernetes
+// strictement positive et ne dépassant pas max
+func validateQuantity(field, value string, max resource.Quantity) error {
+	qty, err := resource.ParseQuantity(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %v", field, value, err)
+	}
+	if qty.Sign() <= 0 {
+		return fmt.Errorf("%s must be positive", field)
+	}
+	if qty.Cmp(max) > 0 {
+		return fmt.Errorf("%s %s exceeds maximum %s", field, value, max.String())
+	}
+	return nil
+}
+
+func limitFromEnv(name, defaultValue string) resource.Quantity {
+	if value := os.Getenv(name); value != "" {
+		if qty, err := resource.ParseQuantity(value); err == nil {
+			return qty
+		}
+	}
+	return resource.MustParse(defaultValue)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"backend/models"
+	"testing"
+)
+
+func validTestChallenge() *models.Challenge {
+	return &models.Challenge{
+		Name:        "XSS Reflective",
+		Difficulty:  "easy",
+		Points:      100,
+		Flag:        "CTF{xss_reflected_pwned}",
+		DockerImage: "registry.local:5000/ctf/xss-vuln:1.2",
+		Port:        80,
+		CPULimit:    "500m",
+		MemoryLimit: "256Mi",
+		TimeLimit:   3600,
+	}
+}
+
+func TestValidateChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*models.Challenge)
+		wantErr bool
+	}{
+		{"valid", func(c *models.Challenge) {}, false},
+		{"file only challenge", func(c *models.Challenge) { c.DockerImage, c.Port, c.CPULimit = "", 0, "" }, false},
+		{"image with digest", func(c *models.Challenge) {
+			c.DockerImage = "xss-vuln@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		}, false},
+		{"uppercase image", func(c *models.Challenge) { c.DockerImage = "XSS-Vuln" }, true},
+		{"image with shell characters", func(c *models.Challenge) { c.DockerImage = "nginx; rm -rf /" }, true},
+		{"port out of range", func(c *models.Challenge) { c.Port = 70000 }, true},
+		{"invalid cpu", func(c *models.Challenge) { c.CPULimit = "lots" }, true},
+		{"cpu above maximum", func(c *models.Challenge) { c.CPULimit = "8" }, true},
+		{"memory above maximum", func(c *models.Challenge) { c.MemoryLimit = "16Gi" }, true},
+		{"time limit too short", func(c *models.Challenge) { c.TimeLimit = 10 }, true},
+		{"flag without prefix", func(c *models.Challenge) { c.Flag = "xss_reflected_pwned" }, true},
+		{"flag with whitespace", func(c *models.Challenge) { c.Flag = "CTF{xss pwned}" }, true},
+		{"placeholder points", func(c *models.Challenge) { c.Points = 0 }, true},
+		{"capitalised difficulty", func(c *models.Challenge) { c.Difficulty = " Medium" }, false},
+		{"unknown difficulty", func(c *models.Challenge) { c.Difficulty = "insane" }, true},
+		{"missing difficulty", func(c *models.Challenge) { c.Difficulty = "" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := validTestChallenge()
+			tt.modify(challenge)
+			err := ValidateChallenge(challenge)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateChallenge() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}